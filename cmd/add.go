@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/opensourcecorp/vdm/internal/message"
+	"github.com/opensourcecorp/vdm/internal/vdmspec"
+	"github.com/spf13/cobra"
+)
+
+var addCmd = &cobra.Command{
+	Use:   "add <remote>",
+	Short: "Add a remote to the spec file, and sync it",
+	Long: `Add a remote to the end of the spec file (leaving its comments & formatting
+alone), and then sync just that remote. If --type isn't provided, it's inferred
+from the remote: anything with a --version, or that looks like a git URL, is a
+git remote, and otherwise a URL that ends in a file name is a file remote. If
+--local-path isn't provided, the remote is placed under ./deps, named after the
+last element of the remote.`,
+	Args: cobra.ExactArgs(1),
+	RunE: addExecute,
+}
+
+type addFlags struct {
+	Type      string
+	Version   string
+	LocalPath string
+}
+
+// AddFlagValues contains an initalized [addFlags] struct with populated values.
+var AddFlagValues addFlags
+
+// Flag name keys
+const (
+	typeFlagKey      string = "type"
+	versionFlagKey   string = "version"
+	localPathFlagKey string = "local-path"
+)
+
+func init() {
+	addCmd.Flags().StringVar(&AddFlagValues.Type, typeFlagKey, "", fmt.Sprintf("Remote type, one of '%s' or '%s' (inferred if not provided)", vdmspec.GitType, vdmspec.FileType))
+	addCmd.Flags().StringVar(&AddFlagValues.Version, versionFlagKey, "", "Version of a git remote (defaults to 'latest')")
+	addCmd.Flags().StringVar(&AddFlagValues.LocalPath, localPathFlagKey, "", "Local path to place the remote at (defaults to one under ./deps)")
+}
+
+func addExecute(_ *cobra.Command, args []string) error {
+	MaybeSetDebug()
+	if err := add(args[0]); err != nil {
+		return fmt.Errorf("executing add command: %w", err)
+	}
+	return nil
+}
+
+// add appends a new remote to the spec file, and syncs it.
+func add(remoteURL string) error {
+	remote := vdmspec.Remote{
+		Type:      AddFlagValues.Type,
+		Remote:    remoteURL,
+		Version:   AddFlagValues.Version,
+		LocalPath: AddFlagValues.LocalPath,
+	}
+
+	if remote.Type == "" && inferRemoteType(remote) == vdmspec.FileType {
+		message.Infof("%s: inferred type '%s' from the remote (pass --%s to override)", remote.Remote, vdmspec.FileType, typeFlagKey)
+		remote.Type = vdmspec.FileType
+	}
+	isGit := remote.Type == vdmspec.GitType || remote.Type == ""
+	if isGit && remote.Version == "" {
+		message.Infof("%s: no --%s provided, so using 'latest'", remote.Remote, versionFlagKey)
+		remote.Version = "latest"
+	}
+	if remote.LocalPath == "" {
+		name := remoteBaseName(remote)
+		if name == "" {
+			return fmt.Errorf("couldn't work out a local path from remote '%s', so please provide one with --%s", remote.Remote, localPathFlagKey)
+		}
+		remote.LocalPath = "./" + path.Join("deps", name)
+		message.Infof("%s: no --%s provided, so using '%s'", remote.Remote, localPathFlagKey, remote.LocalPath)
+	}
+
+	spec, err := vdmspec.GetSpecFromFile(RootFlagValues.SpecFilePath)
+	if err != nil {
+		return fmt.Errorf("getting specs from spec file: %w", err)
+	}
+	for _, existing := range spec.Remotes {
+		if filepath.Clean(existing.LocalPath) == filepath.Clean(remote.LocalPath) {
+			return fmt.Errorf("the spec file already has a remote at local path '%s' (%s)", remote.LocalPath, existing.OpMsg())
+		}
+	}
+
+	specFile, err := vdmspec.ReadSpecFile(RootFlagValues.SpecFilePath)
+	if err != nil {
+		return err
+	}
+	original, err := vdmspec.ReadSpecFile(RootFlagValues.SpecFilePath)
+	if err != nil {
+		return err
+	}
+	if err := specFile.AddRemote(remote); err != nil {
+		return fmt.Errorf("adding %s to spec file: %w", remote.OpMsg(), err)
+	}
+
+	newSpec, err := specFile.Spec()
+	if err != nil {
+		return fmt.Errorf("reading edited spec file: %w", err)
+	}
+	if err := newSpec.Validate(); err != nil {
+		return fmt.Errorf("spec file would be malformed with %s added, so not writing it: %w", remote.OpMsg(), err)
+	}
+
+	if err := specFile.Write(); err != nil {
+		return err
+	}
+	message.Infof("%s: Added to spec file", remote.OpMsg())
+
+	err = syncSelected(func(r vdmspec.Remote) bool {
+		return filepath.Clean(r.LocalPath) == filepath.Clean(remote.LocalPath)
+	}, nil)
+	if err != nil {
+		// Don't leave a remote in the spec file that can't be synced
+		if restoreErr := original.Write(); restoreErr != nil {
+			return fmt.Errorf("%w (and restoring the spec file also failed: %v)", err, restoreErr)
+		}
+		message.Infof("%s: Removed from spec file again, since it failed to sync", remote.OpMsg())
+		return err
+	}
+
+	return nil
+}
+
+// inferRemoteType works out whether a remote is a git repository or a single
+// file. Anything with a version, or that looks like a git URL (like 'git@...',
+// 'git://...', or ending in '.git') is git, and otherwise anything whose URL
+// path ends in a file extension is a file.
+func inferRemoteType(remote vdmspec.Remote) string {
+	if remote.Version != "" ||
+		strings.HasPrefix(remote.Remote, "git@") ||
+		strings.HasPrefix(remote.Remote, "git://") ||
+		strings.HasSuffix(remote.Remote, ".git") {
+		return vdmspec.GitType
+	}
+
+	remoteURL, err := url.Parse(remote.Remote)
+	if err == nil && path.Ext(remoteURL.Path) != "" {
+		return vdmspec.FileType
+	}
+
+	return vdmspec.GitType
+}
+
+// remoteBaseName returns the last element of the remote, without any query
+// string or '.git' suffix, to name its default local path after.
+func remoteBaseName(remote vdmspec.Remote) string {
+	name := remote.Remote
+	if remoteURL, err := url.Parse(remote.Remote); err == nil && remoteURL.Path != "" {
+		name = remoteURL.Path
+	}
+	name = strings.TrimRight(name, "/")
+	if i := strings.LastIndexAny(name, "/:"); i >= 0 {
+		name = name[i+1:]
+	}
+
+	return strings.TrimSuffix(name, ".git")
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/opensourcecorp/vdm/internal/vdmspec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// resetAddFlags puts the add flags back to their defaults, both now and when the
+// test finishes.
+func resetAddFlags(t *testing.T) {
+	t.Helper()
+
+	AddFlagValues = addFlags{}
+	t.Cleanup(func() { AddFlagValues = addFlags{} })
+}
+
+func TestInferRemoteType(t *testing.T) {
+	for _, tc := range []struct {
+		remote vdmspec.Remote
+		want   string
+	}{
+		{remote: vdmspec.Remote{Remote: "https://github.com/opensourcecorp/vdm"}, want: vdmspec.GitType},
+		{remote: vdmspec.Remote{Remote: "https://github.com/opensourcecorp/vdm.git"}, want: vdmspec.GitType},
+		{remote: vdmspec.Remote{Remote: "git@github.com:opensourcecorp/vdm"}, want: vdmspec.GitType},
+		{remote: vdmspec.Remote{Remote: "https://example.com/some.file", Version: "v1.0.0"}, want: vdmspec.GitType},
+		{remote: vdmspec.Remote{Remote: "https://example.com/some.file"}, want: vdmspec.FileType},
+		{remote: vdmspec.Remote{Remote: "https://example.com/some.file?download=true"}, want: vdmspec.FileType},
+	} {
+		assert.Equal(t, tc.want, inferRemoteType(tc.remote), tc.remote.Remote)
+	}
+}
+
+func TestRemoteBaseName(t *testing.T) {
+	for remote, want := range map[string]string{
+		"https://github.com/opensourcecorp/vdm":      "vdm",
+		"https://github.com/opensourcecorp/vdm.git/": "vdm",
+		"git@github.com:opensourcecorp/vdm.git":      "vdm",
+		"git@github.com:vdm.git":                     "vdm",
+		"https://example.com/some.file?download=1":   "some.file",
+		"https://example.com/":                       "",
+	} {
+		assert.Equal(t, want, remoteBaseName(vdmspec.Remote{Remote: remote}), remote)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	server := newTestFileServer(t)
+	remoteURL, _ := newTestGitServer(t)
+
+	const specContents = `# vendored deps
+remotes:
+  # the first one
+  - type: "file"
+    remote: %q
+    local_path: "./deps/first.file"
+
+# end of deps
+`
+	setup := func(t *testing.T) {
+		chdirTemp(t)
+		resetSyncFlags(t)
+		resetAddFlags(t)
+
+		require.NoError(t, os.WriteFile("vdm.yaml", []byte(fmt.Sprintf(specContents, server.URL+"/first.file")), 0644))
+		RootFlagValues.SpecFilePath = "./vdm.yaml"
+		require.NoError(t, sync())
+	}
+
+	t.Run("file remote is appended and synced", func(t *testing.T) {
+		setup(t)
+		require.NoError(t, add(server.URL+"/second.file"))
+
+		got, err := os.ReadFile(RootFlagValues.SpecFilePath)
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf(`# vendored deps
+remotes:
+  # the first one
+  - type: "file"
+    remote: %q
+    local_path: "./deps/first.file"
+  - type: file
+    remote: %s
+    local_path: ./deps/second.file
+
+# end of deps
+`, server.URL+"/first.file", server.URL+"/second.file"), string(got))
+
+		contents, err := os.ReadFile("./deps/second.file")
+		require.NoError(t, err)
+		assert.Equal(t, "content from /second.file", string(contents))
+	})
+
+	t.Run("git remote is appended and synced", func(t *testing.T) {
+		setup(t)
+		AddFlagValues.Version = "main"
+		AddFlagValues.LocalPath = "./deps/repo"
+		require.NoError(t, add(remoteURL))
+
+		spec, err := vdmspec.GetSpecFromFile(RootFlagValues.SpecFilePath)
+		require.NoError(t, err)
+		require.Len(t, spec.Remotes, 2)
+		assert.Equal(t, vdmspec.Remote{Remote: remoteURL, Version: "main", LocalPath: "./deps/repo"}, spec.Remotes[1])
+
+		contents, err := os.ReadFile("./deps/repo/contents.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "first", string(contents))
+	})
+
+	t.Run("existing local path is an error", func(t *testing.T) {
+		setup(t)
+		AddFlagValues.LocalPath = "deps/first.file"
+		assert.Error(t, add(server.URL+"/other.file"))
+	})
+
+	t.Run("spec file is restored if sync fails", func(t *testing.T) {
+		setup(t)
+		before, err := os.ReadFile(RootFlagValues.SpecFilePath)
+		require.NoError(t, err)
+
+		AddFlagValues.Type = vdmspec.GitType
+		AddFlagValues.LocalPath = "./deps/not-a-repo"
+		assert.Error(t, add(server.URL+"/not-a-repo"))
+
+		after, err := os.ReadFile(RootFlagValues.SpecFilePath)
+		require.NoError(t, err)
+		assert.Equal(t, string(before), string(after))
+	})
+}
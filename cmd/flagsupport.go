@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/opensourcecorp/vdm/internal/message"
@@ -17,3 +18,20 @@ func MaybeSetDebug() {
 		}
 	}
 }
+
+// Output formats, for commands that support the --output flag
+const (
+	outputFormatText string = "text"
+	outputFormatJSON string = "json"
+)
+
+// checkOutputFormat returns an error if the provided output format isn't one
+// that vdm knows how to print.
+func checkOutputFormat(format string) error {
+	switch format {
+	case outputFormatText, outputFormatJSON:
+		return nil
+	default:
+		return fmt.Errorf("unrecognized output format '%s', must be one of '%s' or '%s'", format, outputFormatText, outputFormatJSON)
+	}
+}
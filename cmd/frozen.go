@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/opensourcecorp/vdm/internal/message"
+	"github.com/opensourcecorp/vdm/internal/remotes"
+	"github.com/opensourcecorp/vdm/internal/vdmspec"
+)
+
+// checkFrozen verifies that a sync would change neither the lockfile nor
+// anything on disk, without writing to either. Every difference found is
+// reported before returning an error.
+func checkFrozen(spec vdmspec.Spec, lock vdmspec.Lock, plan syncPlan) error {
+	var diffs []string
+
+	// Anything the plan would do besides skipping would change the disk, and
+	// remotes missing from the lockfile are planned to be re-retrieved.
+	// Revalidated remotes are compared against the lockfile below.
+	for _, op := range plan.Operations {
+		if op.Action != actionSkip && op.Action != actionRevalidate {
+			diffs = append(diffs, fmt.Sprintf("%s: would %s (%s)", op.Remote.OpMsg(), op.Action, op.Reason))
+			continue
+		}
+
+		// Whatever's left alone has to already be what's locked
+		vdmMeta, err := op.Remote.GetVDMMeta()
+		if err != nil {
+			return fmt.Errorf("getting vdm metadata file for %s: %w", op.Remote.OpMsg(), err)
+		}
+		if mismatch := lockMismatch(vdmMeta, op.locked); mismatch != "" {
+			diffs = append(diffs, fmt.Sprintf("%s: %s", op.Remote.OpMsg(), mismatch))
+		}
+	}
+
+	// Even if the disk is up to date with the lockfile, the lockfile itself
+	// might not be up to date with the remotes
+	for _, remote := range spec.Remotes {
+		locked, isLocked := lock.Find(remote)
+		if !isLocked {
+			continue
+		}
+
+		switch remote.Type {
+		case vdmspec.GitType, "":
+			commit, err := remotes.ResolveGitVersion(remote)
+			if err != nil {
+				return fmt.Errorf("resolving git remote version: %w", err)
+			}
+			if commit != "" && commit != locked.Commit {
+				diffs = append(diffs, fmt.Sprintf("%s: version now resolves to commit '%s', but %s has '%s'", remote.OpMsg(), commit, vdmspec.LockFileName, locked.Commit))
+			}
+		case vdmspec.FileType:
+			_, digest, err := remotes.ResolveFileDigest(remote)
+			if err != nil {
+				return fmt.Errorf("resolving file remote digest: %w", err)
+			}
+			if digest != locked.SHA256 {
+				diffs = append(diffs, fmt.Sprintf("%s: contents now have SHA-256 '%s', but %s has '%s'", remote.OpMsg(), digest, vdmspec.LockFileName, locked.SHA256))
+			}
+		}
+	}
+
+LockLoop:
+	for _, locked := range lock.Remotes {
+		for _, remote := range spec.Remotes {
+			if locked.SameSource(remote) {
+				continue LockLoop
+			}
+		}
+		diffs = append(diffs, fmt.Sprintf("%s: in %s, but not in spec file", locked.OpMsg(), vdmspec.LockFileName))
+	}
+
+	if len(diffs) > 0 {
+		for _, diff := range diffs {
+			message.Errorf("frozen: %s", diff)
+		}
+		return fmt.Errorf("--%s was set, but %d difference(s) found between your spec file, %s, and what's on disk", frozenFlagKey, len(diffs), vdmspec.LockFileName)
+	}
+
+	message.Infof("Spec file, %s, and local paths all match; nothing to do", vdmspec.LockFileName)
+	return nil
+}
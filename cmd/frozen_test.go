@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/opensourcecorp/vdm/internal/vdmspec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncFrozen(t *testing.T) {
+	remoteURL, commit := newTestGitServer(t)
+
+	fileContents := "first"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, err := w.Write([]byte(fileContents))
+		require.NoError(t, err)
+	}))
+	defer server.Close()
+
+	gitRemote := vdmspec.Remote{
+		Remote:    remoteURL,
+		Version:   "main",
+		LocalPath: "./deps/git",
+	}
+	fileRemote := vdmspec.Remote{
+		Type:      vdmspec.FileType,
+		Remote:    server.URL + "/some.file",
+		LocalPath: "./deps/some.file",
+	}
+
+	setup := func(t *testing.T) {
+		chdirTemp(t)
+		resetSyncFlags(t)
+
+		writeTestSpec(t, vdmspec.Spec{Remotes: []vdmspec.Remote{gitRemote, fileRemote}})
+	}
+
+	t.Run("fails without writing anything when nothing is synced yet", func(t *testing.T) {
+		setup(t)
+		SyncFlagValues.Frozen = true
+		assert.Error(t, sync())
+
+		for _, path := range []string{gitRemote.LocalPath, fileRemote.LocalPath, vdmspec.LockFileName, vdmspec.StateFileName} {
+			_, err := os.Stat(path)
+			assert.ErrorIs(t, err, os.ErrNotExist, path)
+		}
+	})
+
+	t.Run("passes when everything matches", func(t *testing.T) {
+		setup(t)
+		require.NoError(t, sync())
+
+		SyncFlagValues.Frozen = true
+		assert.NoError(t, sync())
+	})
+
+	t.Run("fails when a remote is added to the spec", func(t *testing.T) {
+		setup(t)
+		require.NoError(t, sync())
+
+		extraRemote := fileRemote
+		extraRemote.LocalPath = "./deps/other.file"
+		writeTestSpec(t, vdmspec.Spec{Remotes: []vdmspec.Remote{gitRemote, fileRemote, extraRemote}})
+
+		SyncFlagValues.Frozen = true
+		assert.Error(t, sync())
+	})
+
+	t.Run("fails when a branch moves", func(t *testing.T) {
+		setup(t)
+		require.NoError(t, sync())
+		commit("moved")
+
+		SyncFlagValues.Frozen = true
+		assert.Error(t, sync())
+	})
+
+	t.Run("fails when a file's contents change", func(t *testing.T) {
+		setup(t)
+		require.NoError(t, sync())
+		fileContents = "changed"
+		defer func() { fileContents = "first" }()
+
+		SyncFlagValues.Frozen = true
+		assert.Error(t, sync())
+	})
+
+	t.Run("fails when the installed commit isn't the locked one", func(t *testing.T) {
+		setup(t)
+		require.NoError(t, sync())
+
+		vdmMeta, err := gitRemote.GetVDMMeta()
+		require.NoError(t, err)
+		require.NotNil(t, vdmMeta.Git)
+		vdmMeta.Git.Commit = strings.Repeat("0", 40)
+		require.NoError(t, vdmMeta.Write())
+
+		SyncFlagValues.Frozen = true
+		assert.Error(t, sync())
+	})
+
+	t.Run("fails when the installed file isn't the locked one", func(t *testing.T) {
+		setup(t)
+		require.NoError(t, sync())
+
+		vdmMeta, err := fileRemote.GetVDMMeta()
+		require.NoError(t, err)
+		require.NotNil(t, vdmMeta.File)
+		vdmMeta.File.SHA256 = strings.Repeat("0", 64)
+		require.NoError(t, vdmMeta.Write())
+
+		SyncFlagValues.Frozen = true
+		assert.Error(t, sync())
+	})
+}
@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/opensourcecorp/vdm/internal/message"
+	"github.com/opensourcecorp/vdm/internal/remotes"
+	"github.com/opensourcecorp/vdm/internal/vdmspec"
+	"github.com/spf13/cobra"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Import dependencies managed by other tools into the spec file",
+	Args:  cobra.NoArgs,
+}
+
+var importSubmodulesCmd = &cobra.Command{
+	Use:   "submodules",
+	Short: "Import git submodules into the spec file",
+	Long: `Add a git remote to the spec file for each submodule in ./.gitmodules, at the
+submodule's path, and pinned to the exact commit that the submodule is at in the
+index. The spec file is created if it doesn't exist yet. Submodules that were
+already imported aren't added again.
+
+With --deinit, the submodules are then deinitialized and removed from the index
+& .gitmodules, and their remotes are synced in their place, so that the working
+tree ends up with the same files as before. Like 'git submodule deinit', this
+stops if a submodule has local modifications. If the remotes can't be synced,
+the submodules are put back the way they were.`,
+	Args: cobra.NoArgs,
+	RunE: importSubmodulesExecute,
+}
+
+type importFlags struct {
+	Deinit bool
+}
+
+// ImportFlagValues contains an initalized [importFlags] struct with populated
+// values.
+var ImportFlagValues importFlags
+
+// Flag name keys
+const (
+	deinitFlagKey string = "deinit"
+)
+
+func init() {
+	importSubmodulesCmd.Flags().BoolVar(&ImportFlagValues.Deinit, deinitFlagKey, false, "Deinitialize & remove the submodules, and sync their remotes in their place")
+	importCmd.AddCommand(importSubmodulesCmd)
+}
+
+func importSubmodulesExecute(_ *cobra.Command, _ []string) error {
+	MaybeSetDebug()
+	if err := importSubmodules(); err != nil {
+		return fmt.Errorf("executing import submodules command: %w", err)
+	}
+	return nil
+}
+
+// importSubmodules adds a remote to the spec file for each git submodule, and
+// optionally replaces the submodules with them.
+func importSubmodules() error {
+	submodules, err := remotes.ListGitSubmodules(".")
+	if err != nil {
+		return fmt.Errorf("listing git submodules: %w", err)
+	}
+	if len(submodules) == 0 {
+		message.Infof("No submodules found in %s, so nothing to import", remotes.GitModulesFileName)
+		return nil
+	}
+
+	var spec vdmspec.Spec
+	_, err = os.Stat(RootFlagValues.SpecFilePath)
+	specExists := err == nil
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("checking for existing spec file: %w", err)
+	}
+	if specExists {
+		spec, err = vdmspec.GetSpecFromFile(RootFlagValues.SpecFilePath)
+		if err != nil {
+			return fmt.Errorf("getting specs from spec file: %w", err)
+		}
+	}
+
+	// Submodules that were imported before are still replaced with --deinit,
+	// but aren't added again
+	var imported []remotes.GitSubmodule
+	var importedRemotes, addedRemotes []vdmspec.Remote
+SubmoduleLoop:
+	for _, submodule := range submodules {
+		remote := submodule.Remote()
+		for _, existing := range spec.Remotes {
+			if filepath.Clean(existing.LocalPath) != filepath.Clean(remote.LocalPath) {
+				continue
+			}
+			if existing.Remote != remote.Remote {
+				message.Warnf("%s: spec file already has a different remote at the path of submodule '%s', so skipping it", existing.OpMsg(), submodule.Name)
+				continue SubmoduleLoop
+			}
+			message.Infof("%s: submodule '%s' is already in the spec file", existing.OpMsg(), submodule.Name)
+			imported = append(imported, submodule)
+			importedRemotes = append(importedRemotes, existing)
+			continue SubmoduleLoop
+		}
+		if submodule.Commit == "" {
+			message.Warnf("%s: submodule '%s' isn't in the index, so it can't be pinned to a commit", remote.OpMsg(), submodule.Name)
+		}
+		imported = append(imported, submodule)
+		importedRemotes = append(importedRemotes, remote)
+		addedRemotes = append(addedRemotes, remote)
+	}
+
+	if len(addedRemotes) > 0 {
+		if err := addImportedRemotes(spec, specExists, addedRemotes); err != nil {
+			return err
+		}
+		for _, remote := range addedRemotes {
+			message.Infof("%s: Added to spec file", remote.OpMsg())
+		}
+	}
+
+	if !ImportFlagValues.Deinit {
+		if len(imported) > 0 {
+			message.Infof("The submodules are still in place -- rerun with --%s to replace them with their remotes", deinitFlagKey)
+		}
+		return nil
+	}
+
+	// If anything goes wrong from here, the submodules that were already removed
+	// are put back, so the working tree isn't left without them
+	gitModules, err := os.ReadFile(remotes.GitModulesFileName)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", remotes.GitModulesFileName, err)
+	}
+	var removed []remotes.GitSubmodule
+	restore := func(cause error) error {
+		if err := remotes.RestoreGitSubmodules(".", gitModules, removed); err != nil {
+			return errors.Join(cause, fmt.Errorf("restoring removed submodules: %w", err))
+		}
+		if len(removed) > 0 {
+			message.Warnf("Restored the %d submodule(s) that were removed, since their remotes couldn't replace them", len(removed))
+		}
+		return cause
+	}
+
+	for _, submodule := range imported {
+		if err := remotes.RemoveGitSubmodule(".", submodule); err != nil {
+			return restore(err)
+		}
+		removed = append(removed, submodule)
+		message.Infof("%s: Removed submodule '%s'", submodule.Remote().OpMsg(), submodule.Name)
+	}
+
+	err = syncSelected(func(r vdmspec.Remote) bool {
+		for _, remote := range importedRemotes {
+			if filepath.Clean(r.LocalPath) == filepath.Clean(remote.LocalPath) {
+				return true
+			}
+		}
+		return false
+	}, nil)
+	if err != nil {
+		return restore(err)
+	}
+
+	return nil
+}
+
+// addImportedRemotes appends the remotes to the spec file (leaving its comments
+// & formatting alone), or writes a new spec file with them if it doesn't exist
+// yet. Either way, the resulting spec is validated before anything is written.
+func addImportedRemotes(spec vdmspec.Spec, specExists bool, addedRemotes []vdmspec.Remote) error {
+	if !specExists {
+		spec.Remotes = addedRemotes
+		if err := spec.Validate(); err != nil {
+			return fmt.Errorf("spec file from submodules would be malformed, so not writing it: %w", err)
+		}
+
+		specContents, err := marshalStarterSpec(spec, specFormatFor(RootFlagValues.SpecFilePath))
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(RootFlagValues.SpecFilePath, specContents, 0644); err != nil {
+			return fmt.Errorf("writing spec file: %w", err)
+		}
+		message.Infof("Wrote spec file to '%s'", RootFlagValues.SpecFilePath)
+
+		return nil
+	}
+
+	specFile, err := vdmspec.ReadSpecFile(RootFlagValues.SpecFilePath)
+	if err != nil {
+		return err
+	}
+	for _, remote := range addedRemotes {
+		if err := specFile.AddRemote(remote); err != nil {
+			return fmt.Errorf("adding %s to spec file: %w", remote.OpMsg(), err)
+		}
+	}
+
+	newSpec, err := specFile.Spec()
+	if err != nil {
+		return fmt.Errorf("reading edited spec file: %w", err)
+	}
+	if err := newSpec.Validate(); err != nil {
+		return fmt.Errorf("spec file would be malformed with the submodules added, so not writing it: %w", err)
+	}
+
+	return specFile.Write()
+}
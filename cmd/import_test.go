@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/opensourcecorp/vdm/internal/vdmspec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// resetImportFlags puts the import flags back to their defaults, both now and
+// when the test finishes.
+func resetImportFlags(t *testing.T) {
+	t.Helper()
+
+	ImportFlagValues = importFlags{}
+	t.Cleanup(func() { ImportFlagValues = importFlags{} })
+}
+
+func TestImportSubmodules(t *testing.T) {
+	remoteURL, commit := newTestGitServer(t)
+	pinned := commit("pinned")
+	commit("newer")
+
+	chdirTemp(t)
+	resetSyncFlags(t)
+	resetImportFlags(t)
+	RootFlagValues.SpecFilePath = "./vdm.yaml"
+
+	runTestGit(t, "init", "--initial-branch=main")
+	runTestGit(t, "submodule", "add", remoteURL, "libs/sub")
+	runTestGit(t, "-C", "libs/sub", "checkout", pinned)
+	runTestGit(t, "add", "libs/sub")
+	runTestGit(t, "commit", "-m", "add submodule")
+
+	wantRemote := vdmspec.Remote{Remote: remoteURL, Version: pinned, LocalPath: "./libs/sub"}
+
+	require.NoError(t, importSubmodules())
+	spec, err := vdmspec.GetSpecFromFile(RootFlagValues.SpecFilePath)
+	require.NoError(t, err)
+	assert.Equal(t, []vdmspec.Remote{wantRemote}, spec.Remotes)
+	assert.Equal(t, "160000", runTestGit(t, "ls-files", "--stage", "libs/sub")[:6], "submodule is left in place")
+
+	t.Run("importing again doesn't add it twice", func(t *testing.T) {
+		require.NoError(t, importSubmodules())
+		spec, err := vdmspec.GetSpecFromFile(RootFlagValues.SpecFilePath)
+		require.NoError(t, err)
+		assert.Equal(t, []vdmspec.Remote{wantRemote}, spec.Remotes)
+	})
+
+	t.Run("--deinit replaces the submodule with its remote", func(t *testing.T) {
+		ImportFlagValues.Deinit = true
+		require.NoError(t, importSubmodules())
+
+		assert.Empty(t, runTestGit(t, "ls-files", "--stage", "libs/sub"))
+		gitModules, err := os.ReadFile(".gitmodules")
+		if err == nil {
+			assert.NotContains(t, string(gitModules), "libs/sub")
+		}
+
+		contents, err := os.ReadFile("./libs/sub/contents.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "pinned", string(contents))
+
+		vdmMeta, err := wantRemote.GetVDMMeta()
+		require.NoError(t, err)
+		assert.Equal(t, wantRemote, vdmMeta.Remote)
+	})
+}
+
+func TestImportSubmodulesDeinitFailure(t *testing.T) {
+	remoteURL, _ := newTestGitServer(t)
+
+	chdirTemp(t)
+	resetSyncFlags(t)
+	resetImportFlags(t)
+	RootFlagValues.SpecFilePath = "./vdm.yaml"
+
+	// The submodule is pinned to a commit that was never pushed, so its remote
+	// can't be synced
+	runTestGit(t, "init", "--initial-branch=main")
+	runTestGit(t, "submodule", "add", remoteURL, "libs/sub")
+	require.NoError(t, os.WriteFile("./libs/sub/contents.txt", []byte("unpushed"), 0644))
+	runTestGit(t, "-C", "libs/sub", "commit", "-am", "unpushed")
+	unpushed := runTestGit(t, "-C", "libs/sub", "rev-parse", "HEAD")
+	runTestGit(t, "add", "libs/sub")
+	runTestGit(t, "commit", "-m", "add submodule")
+
+	ImportFlagValues.Deinit = true
+	require.Error(t, importSubmodules())
+
+	t.Run("submodule is restored", func(t *testing.T) {
+		assert.Equal(t, "160000 "+unpushed, runTestGit(t, "ls-files", "--stage", "libs/sub")[:47])
+		gitModules, err := os.ReadFile(".gitmodules")
+		require.NoError(t, err)
+		assert.Contains(t, string(gitModules), "libs/sub")
+		assert.Equal(t, unpushed, runTestGit(t, "-C", "libs/sub", "rev-parse", "HEAD"))
+
+		contents, err := os.ReadFile("./libs/sub/contents.txt")
+		require.NoError(t, err)
+		assert.Equal(t, "unpushed", string(contents))
+	})
+}
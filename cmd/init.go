@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/opensourcecorp/vdm/internal/message"
+	"github.com/opensourcecorp/vdm/internal/remotes"
+	"github.com/opensourcecorp/vdm/internal/vdmspec"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Write a starter spec file",
+	Long: `Write a starter spec file at the --specfile-path location, with comments on how
+to fill it in. The spec file is YAML, unless --format json is set (or the path
+ends in '.json'). An existing spec file is never overwritten, unless --force is
+set.
+
+With --from-submodules, the spec file is seeded with a git remote for each
+submodule in ./.gitmodules, pinned to the commit it's at in the index. With
+--gitignore, either the remotes' local paths ('local-paths') or their vdm
+metafiles ('metafiles') are added to the .gitignore file next to the spec file.`,
+	Args: cobra.NoArgs,
+	RunE: initExecute,
+}
+
+type initFlags struct {
+	Format         string
+	Force          bool
+	Gitignore      string
+	FromSubmodules bool
+}
+
+// InitFlagValues contains an initalized [initFlags] struct with populated
+// values.
+var InitFlagValues initFlags
+
+// Flag name keys
+const (
+	formatFlagKey         string = "format"
+	gitignoreFlagKey      string = "gitignore"
+	fromSubmodulesFlagKey string = "from-submodules"
+)
+
+// Spec file formats
+const (
+	specFormatYAML string = "yaml"
+	specFormatJSON string = "json"
+)
+
+// What --gitignore can add to the .gitignore file
+const (
+	gitignoreLocalPaths string = "local-paths"
+	gitignoreMetaFiles  string = "metafiles"
+)
+
+func init() {
+	initCmd.Flags().StringVar(&InitFlagValues.Format, formatFlagKey, "", fmt.Sprintf("Spec file format, one of '%s' or '%s' (defaults to '%s', unless the path ends in '.json')", specFormatYAML, specFormatJSON, specFormatYAML))
+	initCmd.Flags().BoolVar(&InitFlagValues.Force, forceFlagKey, false, "Overwrite the spec file if it already exists")
+	initCmd.Flags().StringVar(&InitFlagValues.Gitignore, gitignoreFlagKey, "", fmt.Sprintf("Add the remotes' local paths ('%s') or vdm metafiles ('%s') to .gitignore", gitignoreLocalPaths, gitignoreMetaFiles))
+	initCmd.Flags().BoolVar(&InitFlagValues.FromSubmodules, fromSubmodulesFlagKey, false, fmt.Sprintf("Seed the spec file with the submodules in %s", remotes.GitModulesFileName))
+}
+
+func initExecute(_ *cobra.Command, _ []string) error {
+	MaybeSetDebug()
+	if err := initSpec(); err != nil {
+		return fmt.Errorf("executing init command: %w", err)
+	}
+	return nil
+}
+
+// starterSpecComment heads every YAML spec file written by 'vdm init'.
+const starterSpecComment string = `# This is a vdm spec file. Each remote listed under 'remotes' is retrieved and
+# placed at its 'local_path' when you run 'vdm sync'. You can add remotes here by
+# hand, or with 'vdm add <remote>'. For example:
+#
+#   - remote: "https://github.com/opensourcecorp/go-common"
+#     version: "v0.2.0" # a tag, branch, commit, 'latest', or a constraint like '^0.2'
+#     local_path: "./deps/go-common"
+#
+#   - type: "file"
+#     remote: "https://raw.githubusercontent.com/googleapis/googleapis/master/google/api/http.proto"
+#     local_path: "./deps/proto/http/http.proto"
+#
+# See https://github.com/opensourcecorp/vdm for everything else a remote can set.
+`
+
+// initSpec writes a starter spec file.
+func initSpec() error {
+	specFilePath := RootFlagValues.SpecFilePath
+
+	format := InitFlagValues.Format
+	if format == "" {
+		format = specFormatFor(specFilePath)
+	}
+	if format != specFormatYAML && format != specFormatJSON {
+		return fmt.Errorf("--%s must be one of '%s' or '%s', but was '%s'", formatFlagKey, specFormatYAML, specFormatJSON, format)
+	}
+	switch InitFlagValues.Gitignore {
+	case "", gitignoreLocalPaths, gitignoreMetaFiles:
+	default:
+		return fmt.Errorf("--%s must be one of '%s' or '%s', but was '%s'", gitignoreFlagKey, gitignoreLocalPaths, gitignoreMetaFiles, InitFlagValues.Gitignore)
+	}
+
+	if _, err := os.Stat(specFilePath); err == nil && !InitFlagValues.Force {
+		return fmt.Errorf("spec file '%s' already exists, so not overwriting it (use --%s to overwrite it anyway)", specFilePath, forceFlagKey)
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("checking for existing spec file: %w", err)
+	}
+
+	spec := vdmspec.Spec{Remotes: []vdmspec.Remote{}}
+	if InitFlagValues.FromSubmodules {
+		submodules, err := remotes.ListGitSubmodules(".")
+		if err != nil {
+			return fmt.Errorf("listing git submodules: %w", err)
+		}
+		for _, submodule := range submodules {
+			remote := submodule.Remote()
+			message.Infof("%s: Found submodule '%s'", remote.OpMsg(), submodule.Name)
+			spec.Remotes = append(spec.Remotes, remote)
+		}
+		if err := spec.Validate(); err != nil {
+			return fmt.Errorf("spec file from submodules would be malformed, so not writing it: %w", err)
+		}
+	}
+
+	specContents, err := marshalStarterSpec(spec, format)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(specFilePath), 0755); err != nil {
+		return fmt.Errorf("making directory for spec file: %w", err)
+	}
+	if err := os.WriteFile(specFilePath, specContents, 0644); err != nil {
+		return fmt.Errorf("writing spec file: %w", err)
+	}
+	message.Infof("Wrote spec file to '%s'", specFilePath)
+
+	if InitFlagValues.Gitignore != "" {
+		if err := addToGitignore(spec, InitFlagValues.Gitignore); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// specFormatFor returns the format of the spec file at the provided path, going
+// by its extension.
+func specFormatFor(specFilePath string) string {
+	if strings.EqualFold(filepath.Ext(specFilePath), ".json") {
+		return specFormatJSON
+	}
+	return specFormatYAML
+}
+
+// marshalStarterSpec returns the spec as the contents of a spec file in the
+// provided format. YAML spec files are headed by [starterSpecComment].
+func marshalStarterSpec(spec vdmspec.Spec, format string) ([]byte, error) {
+	if format == specFormatJSON {
+		specJSON, err := json.MarshalIndent(spec, "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("marshalling spec to JSON: %w", err)
+		}
+		return append(specJSON, '\n'), nil
+	}
+
+	if len(spec.Remotes) == 0 {
+		return []byte(starterSpecComment + "remotes: []\n"), nil
+	}
+
+	var specYAML strings.Builder
+	encoder := yaml.NewEncoder(&specYAML)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(spec); err != nil {
+		return nil, fmt.Errorf("marshalling spec to YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("marshalling spec to YAML: %w", err)
+	}
+
+	return []byte(starterSpecComment + specYAML.String()), nil
+}
+
+// addToGitignore appends patterns for what --gitignore selected to the
+// .gitignore file next to the spec file, skipping any that are already in it.
+// Local paths are assumed to be relative to that directory. If there are no
+// remotes yet, ./deps is ignored, since that's where 'vdm add' puts them by
+// default.
+func addToGitignore(spec vdmspec.Spec, what string) error {
+	var patterns []string
+	switch what {
+	case gitignoreLocalPaths:
+		for _, remote := range spec.Remotes {
+			localPath := path.Clean(filepath.ToSlash(remote.LocalPath))
+			if strings.HasPrefix(localPath, "../") || path.IsAbs(localPath) {
+				message.Warnf("%s: local path is outside of the spec file's directory, so not adding it to .gitignore", remote.OpMsg())
+				continue
+			}
+			patterns = append(patterns, "/"+localPath)
+		}
+		if len(spec.Remotes) == 0 {
+			patterns = append(patterns, "/deps/")
+		}
+	case gitignoreMetaFiles:
+		patterns = append(patterns, vdmspec.MetaFileName, vdmspec.MetaFileName+"_*")
+	}
+
+	gitignorePath := filepath.Join(filepath.Dir(RootFlagValues.SpecFilePath), ".gitignore")
+	existing, err := os.ReadFile(gitignorePath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("reading .gitignore: %w", err)
+	}
+	existingLines := make(map[string]bool)
+	for _, line := range strings.Split(string(existing), "\n") {
+		existingLines[strings.TrimSpace(line)] = true
+	}
+
+	var toAdd strings.Builder
+	for _, pattern := range patterns {
+		if existingLines[pattern] {
+			message.Debugf("'%s' is already in .gitignore", pattern)
+			continue
+		}
+		existingLines[pattern] = true
+		toAdd.WriteString(pattern + "\n")
+	}
+	if toAdd.Len() == 0 {
+		message.Infof("Everything is already in '%s'", gitignorePath)
+		return nil
+	}
+
+	contents := string(existing)
+	if contents != "" && !strings.HasSuffix(contents, "\n") {
+		contents += "\n"
+	}
+	if contents != "" {
+		contents += "\n"
+	}
+	contents += "# Managed by vdm\n" + toAdd.String()
+
+	if err := os.WriteFile(gitignorePath, []byte(contents), 0644); err != nil {
+		return fmt.Errorf("writing .gitignore: %w", err)
+	}
+	message.Infof("Added %d pattern(s) to '%s'", strings.Count(toAdd.String(), "\n"), gitignorePath)
+
+	return nil
+}
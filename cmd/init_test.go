@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/opensourcecorp/vdm/internal/vdmspec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// resetInitFlags puts the init flags back to their defaults, both now and when
+// the test finishes.
+func resetInitFlags(t *testing.T) {
+	t.Helper()
+
+	InitFlagValues = initFlags{}
+	t.Cleanup(func() { InitFlagValues = initFlags{} })
+}
+
+// runTestGit runs a git command in the current directory, failing the test if
+// it errors.
+func runTestGit(t *testing.T, args ...string) string {
+	t.Helper()
+
+	args = append([]string{"-c", "user.name=vdm", "-c", "user.email=vdm@example.com"}, args...)
+	output, err := exec.Command("git", args...).CombinedOutput()
+	require.NoError(t, err, string(output))
+
+	return strings.TrimSpace(string(output))
+}
+
+// addTestSubmodule declares a submodule in the current repository's
+// .gitmodules, and adds a gitlink for it to the index, without cloning
+// anything.
+func addTestSubmodule(t *testing.T, name, url, commit string) {
+	t.Helper()
+
+	runTestGit(t, "config", "--file", ".gitmodules", "submodule."+name+".path", name)
+	runTestGit(t, "config", "--file", ".gitmodules", "submodule."+name+".url", url)
+	runTestGit(t, "update-index", "--add", "--cacheinfo", "160000,"+commit+","+name)
+}
+
+func TestInit(t *testing.T) {
+	setup := func(t *testing.T) {
+		chdirTemp(t)
+		resetInitFlags(t)
+		RootFlagValues.SpecFilePath = "./vdm.yaml"
+	}
+
+	t.Run("starter spec file is valid and empty", func(t *testing.T) {
+		setup(t)
+		require.NoError(t, initSpec())
+
+		specContents, err := os.ReadFile(RootFlagValues.SpecFilePath)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(string(specContents), starterSpecComment))
+
+		spec, err := vdmspec.GetSpecFromFile(RootFlagValues.SpecFilePath)
+		require.NoError(t, err)
+		assert.Empty(t, spec.Remotes)
+		assert.NoError(t, spec.Validate())
+	})
+
+	t.Run("existing spec file is not overwritten", func(t *testing.T) {
+		setup(t)
+		require.NoError(t, os.WriteFile(RootFlagValues.SpecFilePath, []byte("remotes: []\n"), 0644))
+		assert.Error(t, initSpec())
+
+		specContents, err := os.ReadFile(RootFlagValues.SpecFilePath)
+		require.NoError(t, err)
+		assert.Equal(t, "remotes: []\n", string(specContents))
+
+		t.Run("unless forced", func(t *testing.T) {
+			InitFlagValues.Force = true
+			require.NoError(t, initSpec())
+
+			specContents, err := os.ReadFile(RootFlagValues.SpecFilePath)
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(string(specContents), starterSpecComment))
+		})
+	})
+
+	t.Run("json format", func(t *testing.T) {
+		setup(t)
+		RootFlagValues.SpecFilePath = "./vdm.json"
+		require.NoError(t, initSpec())
+
+		specContents, err := os.ReadFile(RootFlagValues.SpecFilePath)
+		require.NoError(t, err)
+		var spec vdmspec.Spec
+		require.NoError(t, json.Unmarshal(specContents, &spec))
+		assert.Empty(t, spec.Remotes)
+	})
+
+	t.Run("unknown format is an error", func(t *testing.T) {
+		setup(t)
+		InitFlagValues.Format = "toml"
+		assert.Error(t, initSpec())
+	})
+
+	t.Run("seeded from submodules", func(t *testing.T) {
+		setup(t)
+		runTestGit(t, "init")
+		commit := strings.Repeat("a", 40)
+		addTestSubmodule(t, "libs/common", "https://example.com/common.git", commit)
+		InitFlagValues.FromSubmodules = true
+		require.NoError(t, initSpec())
+
+		spec, err := vdmspec.GetSpecFromFile(RootFlagValues.SpecFilePath)
+		require.NoError(t, err)
+		assert.Equal(t, []vdmspec.Remote{{
+			Remote:    "https://example.com/common.git",
+			Version:   commit,
+			LocalPath: "./libs/common",
+		}}, spec.Remotes)
+
+		t.Run("with local paths in .gitignore", func(t *testing.T) {
+			require.NoError(t, os.WriteFile(".gitignore", []byte("/bin"), 0644))
+			InitFlagValues.Force = true
+			InitFlagValues.Gitignore = gitignoreLocalPaths
+			require.NoError(t, initSpec())
+			// Running it again doesn't add anything twice
+			require.NoError(t, initSpec())
+
+			gitignore, err := os.ReadFile(".gitignore")
+			require.NoError(t, err)
+			assert.Equal(t, "/bin\n\n# Managed by vdm\n/libs/common\n", string(gitignore))
+		})
+	})
+
+	t.Run("metafiles in .gitignore", func(t *testing.T) {
+		setup(t)
+		InitFlagValues.Gitignore = gitignoreMetaFiles
+		require.NoError(t, initSpec())
+
+		gitignore, err := os.ReadFile(".gitignore")
+		require.NoError(t, err)
+		assert.Equal(t, "# Managed by vdm\nVDMMETA\nVDMMETA_*\n", string(gitignore))
+	})
+}
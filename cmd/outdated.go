@@ -0,0 +1,266 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/opensourcecorp/vdm/internal/message"
+	"github.com/opensourcecorp/vdm/internal/remotes"
+	"github.com/opensourcecorp/vdm/internal/vdmspec"
+	"github.com/spf13/cobra"
+)
+
+var outdatedCmd = &cobra.Command{
+	Use:   "outdated",
+	Short: "List git remotes with newer upstream versions",
+	Long: `List git remotes that have newer versions upstream. Remotes pinned to a
+semver tag are compared against the newest semver tag on the remote, remotes
+with a version constraint are checked for a newer tag that satisfies it than the
+one in the lockfile, and remotes that track a branch (or 'latest') are checked
+for whether the branch has moved since the commit in the lockfile. Nothing is
+retrieved or changed.`,
+	RunE: outdatedExecute,
+}
+
+type outdatedFlags struct {
+	Output         string
+	FailOnOutdated bool
+}
+
+// OutdatedFlagValues contains an initalized [outdatedFlags] struct with
+// populated values.
+var OutdatedFlagValues outdatedFlags
+
+// Flag name keys
+const (
+	failOnOutdatedFlagKey string = "fail-on-outdated"
+)
+
+func init() {
+	outdatedCmd.Flags().StringVarP(&OutdatedFlagValues.Output, outputFlagKey, "o", outputFormatText, "Output format, one of 'text' or 'json'")
+	outdatedCmd.Flags().BoolVar(&OutdatedFlagValues.FailOnOutdated, failOnOutdatedFlagKey, false, "Exit non-zero if any remotes are outdated")
+}
+
+func outdatedExecute(_ *cobra.Command, _ []string) error {
+	MaybeSetDebug()
+	if err := outdated(); err != nil {
+		return fmt.Errorf("executing outdated command: %w", err)
+	}
+	return nil
+}
+
+// versionKind is what sort of revision a git remote's version names.
+type versionKind string
+
+// Version kinds
+const (
+	versionKindSemverTag  versionKind = "semver-tag"
+	versionKindConstraint versionKind = "constraint"
+	versionKindTag        versionKind = "tag"
+	versionKindBranch     versionKind = "branch"
+	versionKindLatest     versionKind = "latest"
+	versionKindCommit     versionKind = "commit"
+)
+
+// outdatedResult is how a single git remote's version compares to upstream.
+type outdatedResult struct {
+	Name      string      `json:"name"`
+	Remote    string      `json:"remote"`
+	LocalPath string      `json:"local_path"`
+	Version   string      `json:"version"`
+	Kind      versionKind `json:"kind"`
+	// Latest is the newest semver tag for remotes pinned to one, the newest tag
+	// that satisfies a version constraint, or the commit that a branch
+	// currently points to.
+	Latest string `json:"latest,omitempty"`
+	// Locked is the commit recorded in the lockfile, if any.
+	Locked string `json:"locked,omitempty"`
+	// LockedTag is the tag that a version constraint resolved to in the
+	// lockfile, if any.
+	LockedTag string `json:"locked_tag,omitempty"`
+	Outdated  bool   `json:"outdated"`
+	// Error is why the remote's version couldn't be compared to upstream, if
+	// it couldn't be.
+	Error string `json:"error,omitempty"`
+}
+
+// outdatedReport is how every git remote's version compares to upstream.
+type outdatedReport struct {
+	Remotes []outdatedResult `json:"remotes"`
+}
+
+// outdated reports which git remotes have newer versions upstream.
+func outdated() error {
+	if err := checkOutputFormat(OutdatedFlagValues.Output); err != nil {
+		return err
+	}
+
+	spec, err := vdmspec.GetSpecFromFile(RootFlagValues.SpecFilePath)
+	if err != nil {
+		return fmt.Errorf("getting specs from spec file: %w", err)
+	}
+
+	lock, err := vdmspec.GetLockFromFile(vdmspec.LockFilePath(RootFlagValues.SpecFilePath))
+	if err != nil {
+		return fmt.Errorf("getting vdm lockfile: %w", err)
+	}
+
+	var report outdatedReport
+	for _, remote := range spec.Remotes {
+		if remote.Type != vdmspec.GitType && remote.Type != "" {
+			message.Debugf("%s: not a git remote, so skipping", remote.OpMsg())
+			continue
+		}
+
+		result, err := checkOutdated(remote, lock)
+		if err != nil {
+			return fmt.Errorf("checking %s: %w", remote.OpMsg(), err)
+		}
+		if result.Error != "" {
+			message.Warnf("%s: couldn't check for newer versions: %s", remote.OpMsg(), result.Error)
+		}
+		report.Remotes = append(report.Remotes, result)
+	}
+
+	if err := report.print(OutdatedFlagValues.Output); err != nil {
+		return err
+	}
+
+	if OutdatedFlagValues.FailOnOutdated {
+		var count int
+		for _, result := range report.Remotes {
+			if result.Outdated {
+				count++
+			}
+		}
+		if count > 0 {
+			return fmt.Errorf("--%s was set, and %d remote(s) are outdated", failOnOutdatedFlagKey, count)
+		}
+	}
+
+	return nil
+}
+
+// checkOutdated compares a single git remote's version to what's upstream.
+func checkOutdated(remote vdmspec.Remote, lock vdmspec.Lock) (outdatedResult, error) {
+	result := outdatedResult{
+		Name:      remote.DisplayName(),
+		Remote:    remote.Remote,
+		LocalPath: remote.LocalPath,
+		Version:   remote.Version,
+	}
+	if locked, ok := lock.Find(remote); ok {
+		result.Locked, result.LockedTag = locked.Commit, locked.Tag
+	}
+
+	tags, err := remotes.ListGitTags(remote)
+	if err != nil {
+		return outdatedResult{}, fmt.Errorf("listing tags: %w", err)
+	}
+
+	if remote.IsVersionConstraint() {
+		result.Kind = versionKindConstraint
+		result.Latest, err = remote.NewestMatchingTag(tags)
+		if err != nil {
+			// Nothing satisfying the constraint doesn't make the rest of the
+			// remotes any less worth reporting on
+			result.Error = err.Error()
+			return result, nil
+		}
+		result.Outdated = result.LockedTag != "" && result.LockedTag != result.Latest
+		return result, nil
+	}
+
+	if newest, isSemverTag := newestSemverTag(remote.Version, tags, bumpMajor); isSemverTag {
+		result.Kind = versionKindSemverTag
+		result.Latest = newest
+		result.Outdated = newest != remote.Version
+		return result, nil
+	}
+
+	isTag := false
+	for _, tag := range tags {
+		if tag == remote.Version {
+			isTag = true
+			break
+		}
+	}
+
+	commit, err := remotes.ResolveGitVersion(remote)
+	if err != nil {
+		return outdatedResult{}, fmt.Errorf("resolving version: %w", err)
+	}
+
+	switch {
+	case commit == "":
+		// Commits can't move, so there's nothing newer to compare to
+		result.Kind = versionKindCommit
+		return result, nil
+	case remote.Version == "latest":
+		result.Kind = versionKindLatest
+	case isTag:
+		result.Kind = versionKindTag
+	default:
+		result.Kind = versionKindBranch
+	}
+
+	result.Latest = commit
+	result.Outdated = result.Locked != "" && result.Locked != commit
+
+	return result, nil
+}
+
+// print writes out the report in the provided output format.
+func (r outdatedReport) print(format string) error {
+	switch format {
+	case outputFormatJSON:
+		reportJSON, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshalling outdated report to JSON: %w", err)
+		}
+		message.Infof("%s", string(reportJSON))
+	default:
+		if len(r.Remotes) == 0 {
+			message.Infof("No git remotes in spec file")
+			return nil
+		}
+
+		var table strings.Builder
+		tableWriter := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tableWriter, "NAME\tVERSION\tKIND\tLOCKED\tLATEST\tSTATUS")
+		for _, result := range r.Remotes {
+			status := "up to date"
+			switch {
+			case result.Error != "":
+				status = "unknown"
+			case result.Outdated:
+				status = "outdated"
+			case result.Kind == versionKindCommit:
+				status = "pinned"
+			case result.Kind != versionKindSemverTag && result.Locked == "":
+				status = "not locked"
+			}
+
+			// Tags are shown in full, and commits shortened
+			locked, latest := shortResolved(result.Locked), shortResolved(result.Latest)
+			switch result.Kind {
+			case versionKindConstraint:
+				locked, latest = result.LockedTag, result.Latest
+			case versionKindSemverTag:
+				latest = result.Latest
+			}
+			fmt.Fprintf(
+				tableWriter, "%s\t%s\t%s\t%s\t%s\t%s\n",
+				result.Name, result.Version, result.Kind, orDash(locked), orDash(latest), status,
+			)
+		}
+		if err := tableWriter.Flush(); err != nil {
+			return fmt.Errorf("printing outdated table: %w", err)
+		}
+		message.Infof("%s", strings.TrimSuffix(table.String(), "\n"))
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/opensourcecorp/vdm/internal/vdmspec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutdated(t *testing.T) {
+	remoteURL, commit := newTestGitServer(t)
+	firstCommit := commit("v1", "v1.0.0", "not-semver")
+	chdirTemp(t)
+	resetSyncFlags(t)
+
+	tagRemote := vdmspec.Remote{Remote: remoteURL, Version: "v1.0.0", LocalPath: "./deps/tag"}
+	branchRemote := vdmspec.Remote{Remote: remoteURL, Version: "main", LocalPath: "./deps/branch"}
+	otherTagRemote := vdmspec.Remote{Remote: remoteURL, Version: "not-semver", LocalPath: "./deps/other-tag"}
+	commitRemote := vdmspec.Remote{Remote: remoteURL, Version: firstCommit, LocalPath: "./deps/commit"}
+	fileRemote := vdmspec.Remote{Type: vdmspec.FileType, Remote: "https://example.com/some.file", LocalPath: "./deps/some.file"}
+	spec := vdmspec.Spec{Remotes: []vdmspec.Remote{tagRemote, branchRemote, otherTagRemote, commitRemote}}
+	writeTestSpec(t, spec)
+	require.NoError(t, sync())
+
+	// Added after syncing, so that only git remotes get locked & checked
+	spec.Remotes = append(spec.Remotes, fileRemote)
+	writeTestSpec(t, spec)
+
+	lock, err := vdmspec.GetLockFromFile(vdmspec.LockFilePath(RootFlagValues.SpecFilePath))
+	require.NoError(t, err)
+
+	check := func(t *testing.T, remote vdmspec.Remote) outdatedResult {
+		t.Helper()
+		result, err := checkOutdated(remote, lock)
+		require.NoError(t, err)
+		return result
+	}
+
+	t.Run("nothing is outdated right after syncing", func(t *testing.T) {
+		for _, remote := range []vdmspec.Remote{tagRemote, branchRemote, otherTagRemote, commitRemote} {
+			assert.False(t, check(t, remote).Outdated, remote.Version)
+		}
+
+		OutdatedFlagValues.FailOnOutdated = true
+		defer func() { OutdatedFlagValues.FailOnOutdated = false }()
+		assert.NoError(t, outdated())
+	})
+
+	secondCommit := commit("v2", "v1.1.0", "v2.0.0-rc.1")
+
+	t.Run("reports the newest semver tag, ignoring prereleases", func(t *testing.T) {
+		result := check(t, tagRemote)
+		assert.Equal(t, versionKindSemverTag, result.Kind)
+		assert.Equal(t, "v1.1.0", result.Latest)
+		assert.True(t, result.Outdated)
+	})
+
+	t.Run("considers prereleases if already on one", func(t *testing.T) {
+		prerelease := tagRemote
+		prerelease.Version = "v2.0.0-rc.1"
+		result := check(t, prerelease)
+		assert.Equal(t, "v2.0.0-rc.1", result.Latest)
+		assert.False(t, result.Outdated)
+	})
+
+	t.Run("reports branches that moved since the locked commit", func(t *testing.T) {
+		result := check(t, branchRemote)
+		assert.Equal(t, versionKindBranch, result.Kind)
+		assert.Equal(t, firstCommit, result.Locked)
+		assert.Equal(t, secondCommit, result.Latest)
+		assert.True(t, result.Outdated)
+	})
+
+	t.Run("non-semver tags and commits haven't moved", func(t *testing.T) {
+		result := check(t, otherTagRemote)
+		assert.Equal(t, versionKindTag, result.Kind)
+		assert.False(t, result.Outdated)
+
+		result = check(t, commitRemote)
+		assert.Equal(t, versionKindCommit, result.Kind)
+		assert.False(t, result.Outdated)
+	})
+
+	t.Run("constraints that nothing satisfies are reported, not fatal", func(t *testing.T) {
+		unsatisfiable := vdmspec.Remote{Remote: remoteURL, Version: "^9.0", LocalPath: "./deps/unsatisfiable"}
+		result := check(t, unsatisfiable)
+		assert.Equal(t, versionKindConstraint, result.Kind)
+		assert.Contains(t, result.Error, "^9.0")
+		assert.False(t, result.Outdated)
+
+		withUnsatisfiable := spec
+		withUnsatisfiable.Remotes = append([]vdmspec.Remote{unsatisfiable}, spec.Remotes...)
+		writeTestSpec(t, withUnsatisfiable)
+		defer writeTestSpec(t, spec)
+		assert.NoError(t, outdated())
+	})
+
+	t.Run("fails when asked to", func(t *testing.T) {
+		require.NoError(t, outdated())
+
+		OutdatedFlagValues.Output = outputFormatJSON
+		defer func() { OutdatedFlagValues.Output = outputFormatText }()
+		require.NoError(t, outdated())
+
+		OutdatedFlagValues.FailOnOutdated = true
+		defer func() { OutdatedFlagValues.FailOnOutdated = false }()
+		assert.Error(t, outdated())
+	})
+}
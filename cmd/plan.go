@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/opensourcecorp/vdm/internal/message"
+	"github.com/opensourcecorp/vdm/internal/vdmspec"
+)
+
+// syncAction is what a sync will do with a single remote.
+type syncAction string
+
+// Sync actions
+const (
+	// actionCreate retrieves a remote that isn't on disk yet.
+	actionCreate syncAction = "create"
+	// actionUpdate replaces a remote's content on disk.
+	actionUpdate syncAction = "update"
+	// actionRevalidate checks whether a remote has changed upstream since it
+	// was locked, and fails if it has.
+	actionRevalidate syncAction = "revalidate"
+	// actionSkip leaves a remote alone.
+	actionSkip syncAction = "skip"
+	// actionPrune removes a remote that's no longer in the spec from disk.
+	actionPrune syncAction = "prune"
+	// actionUntrack stops tracking a remote that's no longer in the spec,
+	// without removing anything from disk.
+	actionUntrack syncAction = "untrack"
+)
+
+// plannedOp is a single operation that a sync will perform.
+type plannedOp struct {
+	Action syncAction     `json:"action"`
+	Remote vdmspec.Remote `json:"remote"`
+	// Previous is what's currently synced at the remote's local path, if it's
+	// being replaced.
+	Previous *vdmspec.Remote `json:"previous,omitempty"`
+	Reason   string          `json:"reason"`
+
+	// locked is the lockfile entry to honor when applying the operation, if
+	// any.
+	locked vdmspec.LockedRemote
+	// fileMeta is what the server said about a file remote when it was last
+	// retrieved, for revalidating it.
+	fileMeta *vdmspec.FileMeta
+}
+
+// syncPlan is every operation that a sync will perform, in order.
+type syncPlan struct {
+	Operations []plannedOp `json:"operations"`
+}
+
+// planSync works out what a sync needs to do with each remote in the spec, and
+// with each remote that has since been removed from it, without changing
+// anything on disk. Any remotes for which shouldUpdate returns true are
+// planned to be re-resolved instead of honoring the lockfile.
+func planSync(spec vdmspec.Spec, state vdmspec.State, lock vdmspec.Lock, shouldUpdate func(vdmspec.Remote) bool) (syncPlan, error) {
+	var plan syncPlan
+
+	for _, remote := range spec.Remotes {
+		// process stored vdm metafile so we know what operations to actually
+		// perform for existing directories
+		vdmMeta, err := remote.GetVDMMeta()
+		if err != nil {
+			return syncPlan{}, fmt.Errorf("getting vdm metadata file for sync: %w", err)
+		}
+
+		op := plannedOp{Remote: remote}
+		locked, isLocked := lock.Find(remote)
+		updating := shouldUpdate != nil && shouldUpdate(remote)
+		if !updating {
+			op.locked = locked
+		}
+		mismatch := lockMismatch(vdmMeta, locked)
+
+		switch {
+		case vdmMeta.IsZero():
+			op.Action = actionCreate
+			op.Reason = fmt.Sprintf("%s not found at local path", vdmspec.MetaFileName)
+		case !vdmMeta.Remote.Equal(remote):
+			previous := vdmMeta.Remote
+			op.Action = actionUpdate
+			op.Previous = &previous
+			op.Reason = fmt.Sprintf("changing from current local spec '%s'", previous.OpMsg())
+		case remote.KeepGitDir && !vdmMeta.Remote.KeepGitDir:
+			// Turning keep_git_dir off leaves a kept clone alone (see
+			// [vdmspec.Remote.Equal]), but turning it on needs a clone to keep
+			previous := vdmMeta.Remote
+			op.Action = actionUpdate
+			op.Previous = &previous
+			op.Reason = "keeping its .git directory, which wasn't kept before"
+		case updating:
+			previous := vdmMeta.Remote
+			op.Action = actionUpdate
+			op.Previous = &previous
+			op.Reason = fmt.Sprintf("re-resolving, ignoring any entry in %s", vdmspec.LockFileName)
+		case !isLocked:
+			previous := vdmMeta.Remote
+			op.Action = actionUpdate
+			op.Previous = &previous
+			op.Reason = fmt.Sprintf("not found in %s, so re-retrieving it so it can be locked", vdmspec.LockFileName)
+		case mismatch != "":
+			previous := vdmMeta.Remote
+			op.Action = actionUpdate
+			op.Previous = &previous
+			op.Reason = mismatch + ", so checking out what's locked"
+		case remote.Type == vdmspec.FileType:
+			// Unlike git versions, file URLs don't say anything about what
+			// they point to, so the server has to be asked each time
+			previous := vdmMeta.Remote
+			op.Action = actionRevalidate
+			op.Previous = &previous
+			op.Reason = "remote unchanged in spec file, checking for upstream changes"
+			op.fileMeta = vdmMeta.File
+			if op.fileMeta == nil {
+				op.fileMeta = &vdmspec.FileMeta{SHA256: locked.SHA256}
+			}
+		default:
+			op.Action = actionSkip
+			op.Reason = "remote unchanged in spec file"
+		}
+
+		plan.Operations = append(plan.Operations, op)
+	}
+
+	if SyncFlagValues.NoPrune {
+		message.Debugf("--%s was set, so not planning to prune remotes removed from the spec file", noPruneFlagKey)
+		return plan, nil
+	}
+
+OrphanLoop:
+	for _, orphan := range state.Orphans(spec) {
+		op := plannedOp{Remote: orphan}
+
+		for _, remote := range spec.Remotes {
+			if vdmspec.PathsOverlap(orphan.LocalPath, remote.LocalPath) {
+				op.Action = actionUntrack
+				op.Reason = fmt.Sprintf("no longer in spec file, but its local path overlaps with '%s', so not removing it", remote.LocalPath)
+				plan.Operations = append(plan.Operations, op)
+				continue OrphanLoop
+			}
+		}
+
+		hasEdits, err := hasLocalEdits(orphan)
+		if err != nil {
+			return syncPlan{}, fmt.Errorf("checking for local edits: %w", err)
+		}
+		if hasEdits && !SyncFlagValues.Force {
+			op.Action = actionSkip
+			op.Reason = fmt.Sprintf("no longer in spec file, but has local edits, so not removing it (use --%s to remove it anyway)", forceFlagKey)
+		} else {
+			op.Action = actionPrune
+			op.Reason = "no longer in spec file"
+		}
+		plan.Operations = append(plan.Operations, op)
+	}
+
+	return plan, nil
+}
+
+// only returns the plan with every operation on a remote that isn't selected
+// turned into a skip, so that it's left exactly as it is (including its
+// lockfile entry).
+func (p syncPlan) only(isSelected func(vdmspec.Remote) bool) syncPlan {
+	var plan syncPlan
+	for _, op := range p.Operations {
+		if !isSelected(op.Remote) {
+			op = plannedOp{Action: actionSkip, Remote: op.Remote, Reason: "not selected", locked: op.locked}
+		}
+		plan.Operations = append(plan.Operations, op)
+	}
+	return plan
+}
+
+// lockMismatch describes how what a remote's metafile says is installed at its
+// local path differs from its locked entry, or returns an empty string if it
+// doesn't.
+func lockMismatch(vdmMeta vdmspec.VDMMeta, locked vdmspec.LockedRemote) string {
+	switch {
+	case vdmMeta.Git != nil && vdmMeta.Git.Commit != locked.Commit:
+		return fmt.Sprintf("commit '%s' is checked out, but %s has '%s'", vdmMeta.Git.Commit, vdmspec.LockFileName, locked.Commit)
+	case vdmMeta.File != nil && vdmMeta.File.SHA256 != locked.SHA256:
+		return fmt.Sprintf("contents have SHA-256 '%s', but %s has '%s'", vdmMeta.File.SHA256, vdmspec.LockFileName, locked.SHA256)
+	default:
+		return ""
+	}
+}
+
+// hasLocalEdits reports whether the remote's content on disk differs from what
+// vdm recorded when it was synced. Content without a metafile can't be checked,
+// and so is assumed to be edited.
+func hasLocalEdits(remote vdmspec.Remote) (bool, error) {
+	vdmMeta, err := remote.GetVDMMeta()
+	if err != nil {
+		return false, fmt.Errorf("getting vdm metadata file: %w", err)
+	}
+
+	current, err := remote.BuildManifest()
+	if err != nil {
+		return false, fmt.Errorf("building manifest: %w", err)
+	}
+
+	if vdmMeta.IsZero() {
+		return len(current) > 0, nil
+	}
+
+	diff := vdmMeta.Manifest.Diff(current)
+	message.Debugf("%s: local edits check found: %+v", remote.OpMsg(), diff)
+
+	return !diff.IsEmpty(), nil
+}
+
+// print writes out the plan in the provided output format.
+func (p syncPlan) print(format string) error {
+	switch format {
+	case outputFormatJSON:
+		planJSON, err := json.MarshalIndent(p, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshalling plan to JSON: %w", err)
+		}
+		message.Infof("%s", string(planJSON))
+	default:
+		if len(p.Operations) == 0 {
+			message.Infof("No remotes in spec file; nothing to do")
+			return nil
+		}
+		message.Infof("Planned sync operations:")
+		for _, op := range p.Operations {
+			message.Infof("  %-10s  %s (%s)", op.Action, op.Remote.OpMsg(), op.Reason)
+		}
+	}
+
+	return nil
+}
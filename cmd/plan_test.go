@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opensourcecorp/vdm/internal/vdmspec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanSync(t *testing.T) {
+	server := newTestFileServer(t)
+	chdirTemp(t)
+	resetSyncFlags(t)
+
+	unchanged := vdmspec.Remote{Type: vdmspec.FileType, Remote: server.URL + "/unchanged.file", LocalPath: "./deps/unchanged.file"}
+	changed := vdmspec.Remote{Type: vdmspec.FileType, Remote: server.URL + "/v1/changed.file", LocalPath: "./deps/changed.file"}
+	removed := vdmspec.Remote{Type: vdmspec.FileType, Remote: server.URL + "/removed.file", LocalPath: "./deps/removed.file"}
+	writeTestSpec(t, vdmspec.Spec{Remotes: []vdmspec.Remote{unchanged, changed, removed}})
+	require.NoError(t, sync())
+
+	changedAfter := changed
+	changedAfter.Remote = server.URL + "/v2/changed.file"
+	created := vdmspec.Remote{Type: vdmspec.FileType, Remote: server.URL + "/created.file", LocalPath: "./deps/created.file"}
+	spec := vdmspec.Spec{Remotes: []vdmspec.Remote{unchanged, changedAfter, created}}
+	writeTestSpec(t, spec)
+
+	state, err := vdmspec.GetStateFromFile(vdmspec.StateFilePath(RootFlagValues.SpecFilePath))
+	require.NoError(t, err)
+	lock, err := vdmspec.GetLockFromFile(vdmspec.LockFilePath(RootFlagValues.SpecFilePath))
+	require.NoError(t, err)
+
+	t.Run("plans an action for every remote", func(t *testing.T) {
+		plan, err := planSync(spec, state, lock, nil)
+		require.NoError(t, err)
+
+		var got []syncAction
+		for _, op := range plan.Operations {
+			got = append(got, op.Action)
+		}
+		assert.Equal(t, []syncAction{actionRevalidate, actionUpdate, actionCreate, actionPrune}, got)
+		assert.Equal(t, &changed, plan.Operations[1].Previous)
+		assert.Equal(t, removed, plan.Operations[3].Remote)
+	})
+
+	t.Run("updates are planned for selected remotes", func(t *testing.T) {
+		plan, err := planSync(spec, state, lock, func(r vdmspec.Remote) bool { return r.Equal(unchanged) })
+		require.NoError(t, err)
+		assert.Equal(t, actionUpdate, plan.Operations[0].Action)
+		assert.True(t, plan.Operations[0].locked.IsZero())
+	})
+
+	t.Run("no prune operations with --no-prune", func(t *testing.T) {
+		SyncFlagValues.NoPrune = true
+		defer func() { SyncFlagValues.NoPrune = false }()
+
+		plan, err := planSync(spec, state, lock, nil)
+		require.NoError(t, err)
+		assert.Len(t, plan.Operations, 3)
+	})
+
+	t.Run("plan marshals to JSON", func(t *testing.T) {
+		plan, err := planSync(spec, state, lock, nil)
+		require.NoError(t, err)
+
+		planJSON, err := json.Marshal(plan)
+		require.NoError(t, err)
+
+		var got map[string][]map[string]any
+		require.NoError(t, json.Unmarshal(planJSON, &got))
+		assert.Equal(t, "update", got["operations"][1]["action"])
+		assert.Contains(t, got["operations"][1], "previous")
+	})
+
+	t.Run("--dry-run doesn't change anything", func(t *testing.T) {
+		SyncFlagValues.DryRun = true
+		SyncFlagValues.Output = outputFormatJSON
+		defer resetSyncFlags(t)
+
+		require.NoError(t, sync())
+
+		_, err := os.Stat(created.LocalPath)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+		_, err = os.Stat(removed.LocalPath)
+		assert.NoError(t, err)
+	})
+
+	t.Run("bad output format is rejected", func(t *testing.T) {
+		SyncFlagValues.DryRun = true
+		SyncFlagValues.Output = "yaml"
+		defer resetSyncFlags(t)
+
+		assert.Error(t, sync())
+	})
+}
+
+func TestPlanSyncHonorsLock(t *testing.T) {
+	remoteURL, commit := newTestGitServer(t)
+	chdirTemp(t)
+	resetSyncFlags(t)
+	resetUpdateFlags(t)
+
+	remote := vdmspec.Remote{Remote: remoteURL, Version: "main", LocalPath: "./deps/git"}
+	spec := vdmspec.Spec{Remotes: []vdmspec.Remote{remote}}
+	writeTestSpec(t, spec)
+	lockFilePath := vdmspec.LockFilePath(RootFlagValues.SpecFilePath)
+
+	require.NoError(t, sync())
+	lock, err := vdmspec.GetLockFromFile(lockFilePath)
+	require.NoError(t, err)
+	require.Len(t, lock.Remotes, 1)
+	firstCommit := lock.Remotes[0].Commit
+
+	secondCommit := commit("second")
+	require.NoError(t, update([]string{remote.LocalPath}))
+
+	// Stands in for checking out a vdm.lock from an older revision of the
+	// project
+	lock.Remotes[0].Commit = firstCommit
+	require.NoError(t, lock.WriteToFile(lockFilePath))
+
+	t.Run("an installed commit that isn't the locked one is updated", func(t *testing.T) {
+		state, err := vdmspec.GetStateFromFile(vdmspec.StateFilePath(RootFlagValues.SpecFilePath))
+		require.NoError(t, err)
+
+		plan, err := planSync(spec, state, lock, nil)
+		require.NoError(t, err)
+		require.Len(t, plan.Operations, 1)
+		assert.Equal(t, actionUpdate, plan.Operations[0].Action)
+		assert.Contains(t, plan.Operations[0].Reason, firstCommit)
+		assert.Contains(t, plan.Operations[0].Reason, secondCommit)
+	})
+
+	t.Run("sync checks out the locked commit", func(t *testing.T) {
+		require.NoError(t, sync())
+
+		got, err := os.ReadFile(filepath.Join(remote.LocalPath, "contents.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "first", string(got))
+
+		vdmMeta, err := remote.GetVDMMeta()
+		require.NoError(t, err)
+		require.NotNil(t, vdmMeta.Git)
+		assert.Equal(t, firstCommit, vdmMeta.Git.Commit)
+	})
+}
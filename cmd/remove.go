@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/opensourcecorp/vdm/internal/message"
+	"github.com/opensourcecorp/vdm/internal/vdmspec"
+	"github.com/spf13/cobra"
+)
+
+var removeCmd = &cobra.Command{
+	Use:   "remove <remote>",
+	Short: "Remove a remote from the spec file, and prune its files",
+	Long: `Remove a remote from the spec file (leaving the rest of its comments &
+formatting alone), and then prune what vdm placed at its local path. The remote
+can be selected by its name, local path, or remote. Like 'vdm sync', a remote
+with local edits isn't pruned unless --force is set.`,
+	Args: cobra.ExactArgs(1),
+	RunE: removeExecute,
+}
+
+func init() {
+	// Shares sync's flag, since pruning is done by a sync
+	removeCmd.Flags().BoolVar(&SyncFlagValues.Force, forceFlagKey, false, "Prune the remote's files, even if they have local edits")
+}
+
+func removeExecute(_ *cobra.Command, args []string) error {
+	MaybeSetDebug()
+	if err := remove(args[0]); err != nil {
+		return fmt.Errorf("executing remove command: %w", err)
+	}
+	return nil
+}
+
+// remove deletes the selected remote from the spec file, and prunes it.
+func remove(selector string) error {
+	spec, err := vdmspec.GetSpecFromFile(RootFlagValues.SpecFilePath)
+	if err != nil {
+		return fmt.Errorf("getting specs from spec file: %w", err)
+	}
+
+	isSelected, err := selectRemotes(spec, []string{selector}, nil)
+	if err != nil {
+		return err
+	}
+	var matched []int
+	for i, remote := range spec.Remotes {
+		if isSelected(remote) {
+			matched = append(matched, i)
+		}
+	}
+	if len(matched) > 1 {
+		return fmt.Errorf("'%s' matches %d remotes in the spec file, so select the one to remove by its name or local path", selector, len(matched))
+	}
+	removed := spec.Remotes[matched[0]]
+
+	// Checked up front, since once it's gone from the spec file it can't be
+	// selected again to retry with --force
+	hasEdits, err := hasLocalEdits(removed)
+	if err != nil {
+		return fmt.Errorf("checking %s for local edits: %w", removed.OpMsg(), err)
+	}
+	if hasEdits && !SyncFlagValues.Force {
+		return fmt.Errorf("%s has local edits, so not removing it (use --%s to remove it anyway)", removed.OpMsg(), forceFlagKey)
+	}
+
+	specFile, err := vdmspec.ReadSpecFile(RootFlagValues.SpecFilePath)
+	if err != nil {
+		return err
+	}
+	if err := specFile.RemoveRemote(matched[0]); err != nil {
+		return fmt.Errorf("removing %s from spec file: %w", removed.OpMsg(), err)
+	}
+	if _, err := specFile.Spec(); err != nil {
+		return fmt.Errorf("reading edited spec file: %w", err)
+	}
+	if err := specFile.Write(); err != nil {
+		return err
+	}
+	message.Infof("%s: Removed from spec file", removed.OpMsg())
+
+	return syncSelected(func(r vdmspec.Remote) bool {
+		return filepath.Clean(r.LocalPath) == filepath.Clean(removed.LocalPath)
+	}, nil)
+}
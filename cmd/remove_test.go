@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/opensourcecorp/vdm/internal/vdmspec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemove(t *testing.T) {
+	server := newTestFileServer(t)
+
+	const specContents = `# vendored deps
+remotes:
+  # the first one
+  - type: "file"
+    remote: %q
+    local_path: "./deps/first.file"
+
+  # the second one
+  - type: "file"
+    remote: %q
+    local_path: "./deps/second.file"
+`
+	setup := func(t *testing.T) {
+		chdirTemp(t)
+		resetSyncFlags(t)
+
+		contents := fmt.Sprintf(specContents, server.URL+"/first.file", server.URL+"/second.file")
+		require.NoError(t, os.WriteFile("vdm.yaml", []byte(contents), 0644))
+		RootFlagValues.SpecFilePath = "./vdm.yaml"
+		require.NoError(t, sync())
+	}
+
+	t.Run("remote is removed from spec file and pruned", func(t *testing.T) {
+		setup(t)
+		require.NoError(t, remove("deps/first.file"))
+
+		got, err := os.ReadFile(RootFlagValues.SpecFilePath)
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf(`# vendored deps
+remotes:
+  # the second one
+  - type: "file"
+    remote: %q
+    local_path: "./deps/second.file"
+`, server.URL+"/second.file"), string(got))
+
+		_, err = os.Stat("./deps/first.file")
+		assert.ErrorIs(t, err, os.ErrNotExist)
+		_, err = os.Stat("./deps/second.file")
+		assert.NoError(t, err)
+
+		state, err := vdmspec.GetStateFromFile(vdmspec.StateFilePath(RootFlagValues.SpecFilePath))
+		require.NoError(t, err)
+		require.Len(t, state.Remotes, 1)
+		assert.Equal(t, "./deps/second.file", state.Remotes[0].LocalPath)
+	})
+
+	t.Run("unknown remote is an error", func(t *testing.T) {
+		setup(t)
+		assert.Error(t, remove("./deps/nope"))
+	})
+
+	t.Run("remote with local edits is not removed", func(t *testing.T) {
+		setup(t)
+		require.NoError(t, os.WriteFile("./deps/second.file", []byte("my edits"), 0644))
+		before, err := os.ReadFile(RootFlagValues.SpecFilePath)
+		require.NoError(t, err)
+
+		assert.Error(t, remove(server.URL+"/second.file"))
+
+		after, err := os.ReadFile(RootFlagValues.SpecFilePath)
+		require.NoError(t, err)
+		assert.Equal(t, string(before), string(after))
+
+		t.Run("unless forced", func(t *testing.T) {
+			SyncFlagValues.Force = true
+			require.NoError(t, remove(server.URL+"/second.file"))
+
+			_, err := os.Stat("./deps/second.file")
+			assert.ErrorIs(t, err, os.ErrNotExist)
+		})
+	})
+}
@@ -60,7 +60,16 @@ func init() {
 		message.Fatalf("internal error: unable to bind state of flag --%s", debugFlagKey)
 	}
 
+	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(syncCmd)
+	rootCmd.AddCommand(updateCmd)
+	rootCmd.AddCommand(verifyCmd)
+	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(outdatedCmd)
+	rootCmd.AddCommand(addCmd)
+	rootCmd.AddCommand(removeCmd)
+	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(schemaCmd)
 }
 
 // Execute wraps the primary execution logic for vdm's root command, and returns
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/opensourcecorp/vdm/internal/message"
+	"github.com/opensourcecorp/vdm/internal/vdmspec"
+	"github.com/spf13/cobra"
+)
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the JSON Schema for spec files",
+	Long: `Print the JSON Schema for spec files, so that editors can autocomplete and
+validate them. The same schema is published at:
+
+  ` + vdmspec.SchemaID,
+	Args: cobra.NoArgs,
+	RunE: schemaExecute,
+}
+
+func schemaExecute(_ *cobra.Command, _ []string) error {
+	MaybeSetDebug()
+	if err := schema(); err != nil {
+		return fmt.Errorf("executing schema command: %w", err)
+	}
+	return nil
+}
+
+// schema prints the JSON Schema for spec files.
+func schema() error {
+	specSchema, err := vdmspec.Schema()
+	if err != nil {
+		return err
+	}
+	message.Infof("%s", string(specSchema[:len(specSchema)-1]))
+
+	return nil
+}
@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/opensourcecorp/vdm/internal/message"
+	"github.com/opensourcecorp/vdm/internal/vdmspec"
+	"github.com/spf13/cobra"
+)
+
+var statusCmd = &cobra.Command{
+	Use:   "status [remote...]",
+	Short: "Summarize the state of every remote",
+	Long: `Summarize the state of every remote in the spec file (and every remote that was
+removed from it but is still on disk), without retrieving or changing anything.
+Remotes can be selected by their name, local path, or remote, and/or by --tag;
+if none are selected, all remotes are summarized.`,
+	RunE: statusExecute,
+}
+
+type statusFlags struct {
+	Output string
+	Tags   []string
+}
+
+// StatusFlagValues contains an initalized [statusFlags] struct with populated
+// values.
+var StatusFlagValues statusFlags
+
+func init() {
+	statusCmd.Flags().StringVarP(&StatusFlagValues.Output, outputFlagKey, "o", outputFormatText, "Output format, one of 'text' or 'json'")
+	statusCmd.Flags().StringSliceVar(&StatusFlagValues.Tags, tagFlagKey, nil, "Only summarize remotes with this tag (can be repeated)")
+}
+
+func statusExecute(_ *cobra.Command, args []string) error {
+	MaybeSetDebug()
+	if err := status(args); err != nil {
+		return fmt.Errorf("executing status command: %w", err)
+	}
+	return nil
+}
+
+// remoteState summarizes how a remote on disk compares to the spec file.
+type remoteState string
+
+// Remote states
+const (
+	// stateInSync means the remote is synced as specified & locked, and hasn't
+	// been edited since.
+	stateInSync remoteState = "in sync"
+	// stateOutOfDate means the remote is on disk, but not as it's currently
+	// specified or locked.
+	stateOutOfDate remoteState = "out of date"
+	// stateMissing means the remote hasn't been synced at all.
+	stateMissing remoteState = "missing"
+	// stateOrphaned means the remote is no longer in the spec file, but vdm
+	// placed it on disk and it hasn't been pruned yet.
+	stateOrphaned remoteState = "orphaned"
+	// stateDrifted means the remote is synced as specified, but has been edited
+	// since.
+	stateDrifted remoteState = "drifted"
+)
+
+// remoteStatus is a summary of a single remote's state.
+type remoteStatus struct {
+	Name             string      `json:"name"`
+	Type             string      `json:"type"`
+	SpecVersion      string      `json:"spec_version,omitempty"`
+	InstalledVersion string      `json:"installed_version,omitempty"`
+	Resolved         string      `json:"resolved,omitempty"`
+	LocalPath        string      `json:"local_path"`
+	State            remoteState `json:"state"`
+}
+
+// statusReport is a summary of every remote's state.
+type statusReport struct {
+	Remotes []remoteStatus `json:"remotes"`
+}
+
+// status prints a summary of every selected remote's state, without changing
+// anything.
+func status(selectors []string) error {
+	if err := checkOutputFormat(StatusFlagValues.Output); err != nil {
+		return err
+	}
+
+	spec, err := vdmspec.GetSpecFromFile(RootFlagValues.SpecFilePath)
+	if err != nil {
+		return fmt.Errorf("getting specs from spec file: %w", err)
+	}
+
+	state, err := vdmspec.GetStateFromFile(vdmspec.StateFilePath(RootFlagValues.SpecFilePath))
+	if err != nil {
+		return fmt.Errorf("getting vdm state: %w", err)
+	}
+
+	lock, err := vdmspec.GetLockFromFile(vdmspec.LockFilePath(RootFlagValues.SpecFilePath))
+	if err != nil {
+		return fmt.Errorf("getting vdm lockfile: %w", err)
+	}
+
+	isSelected, err := selectRemotes(spec, selectors, StatusFlagValues.Tags)
+	if err != nil {
+		return err
+	}
+
+	report, err := buildStatusReport(spec, state, lock, isSelected)
+	if err != nil {
+		return err
+	}
+
+	return report.print(StatusFlagValues.Output)
+}
+
+// buildStatusReport works out the state of every selected remote in the spec,
+// and of every selected remote that was removed from it but is still tracked.
+func buildStatusReport(spec vdmspec.Spec, state vdmspec.State, lock vdmspec.Lock, isSelected func(vdmspec.Remote) bool) (statusReport, error) {
+	var report statusReport
+
+	for _, remote := range spec.Remotes {
+		if !isSelected(remote) {
+			continue
+		}
+		result := remoteStatus{
+			Name:        remote.DisplayName(),
+			Type:        remoteTypeName(remote),
+			SpecVersion: remote.Version,
+			LocalPath:   remote.LocalPath,
+		}
+
+		vdmMeta, err := remote.GetVDMMeta()
+		if err != nil {
+			return statusReport{}, fmt.Errorf("getting vdm metadata file for %s: %w", remote.OpMsg(), err)
+		}
+		if !vdmMeta.IsZero() {
+			result.InstalledVersion = vdmMeta.Version
+		}
+		// What's resolved is what's actually installed, which may not be
+		// what's locked
+		switch {
+		case vdmMeta.Git != nil:
+			result.Resolved = vdmMeta.Git.Commit
+		case vdmMeta.File != nil:
+			result.Resolved = "sha256:" + vdmMeta.File.SHA256
+		}
+
+		locked, isLocked := lock.Find(remote)
+
+		switch {
+		case vdmMeta.IsZero():
+			result.State = stateMissing
+		case !vdmMeta.Remote.Equal(remote) || !isLocked || lockMismatch(vdmMeta, locked) != "":
+			result.State = stateOutOfDate
+		default:
+			verification, err := verifyRemote(remote)
+			if err != nil {
+				return statusReport{}, fmt.Errorf("checking %s for local edits: %w", remote.OpMsg(), err)
+			}
+			result.State = stateInSync
+			if verification.Status == verifyStatusDrifted {
+				result.State = stateDrifted
+			}
+		}
+
+		report.Remotes = append(report.Remotes, result)
+	}
+
+	for _, orphan := range state.Orphans(spec) {
+		if !isSelected(orphan) {
+			continue
+		}
+		report.Remotes = append(report.Remotes, remoteStatus{
+			Name:             orphan.DisplayName(),
+			Type:             remoteTypeName(orphan),
+			InstalledVersion: orphan.Version,
+			LocalPath:        orphan.LocalPath,
+			State:            stateOrphaned,
+		})
+	}
+
+	return report, nil
+}
+
+// shortResolved shortens a resolved commit or digest, since a short prefix is
+// plenty to tell them apart in a table.
+func shortResolved(resolved string) string {
+	const shortLength = 12
+
+	prefix, digest := "", resolved
+	if strings.HasPrefix(resolved, "sha256:") {
+		prefix, digest = "sha256:", strings.TrimPrefix(resolved, "sha256:")
+	}
+	if len(digest) > shortLength {
+		digest = digest[:shortLength]
+	}
+	return prefix + digest
+}
+
+// orDash returns the string, or a dash in its place if it's empty, so that
+// table cells are never blank.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// remoteTypeName returns the remote's type, accounting for git being the
+// default.
+func remoteTypeName(remote vdmspec.Remote) string {
+	if remote.Type == "" {
+		return vdmspec.GitType
+	}
+	return remote.Type
+}
+
+// print writes out the report in the provided output format.
+func (r statusReport) print(format string) error {
+	switch format {
+	case outputFormatJSON:
+		reportJSON, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshalling status report to JSON: %w", err)
+		}
+		message.Infof("%s", string(reportJSON))
+	default:
+		if len(r.Remotes) == 0 {
+			message.Infof("No remotes in spec file")
+			return nil
+		}
+
+		var table strings.Builder
+		tableWriter := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tableWriter, "NAME\tTYPE\tSPEC VERSION\tINSTALLED VERSION\tRESOLVED\tLOCAL PATH\tSTATE")
+		for _, result := range r.Remotes {
+			fmt.Fprintf(
+				tableWriter, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				result.Name, result.Type, orDash(result.SpecVersion), orDash(result.InstalledVersion),
+				orDash(shortResolved(result.Resolved)), result.LocalPath, result.State,
+			)
+		}
+		if err := tableWriter.Flush(); err != nil {
+			return fmt.Errorf("printing status table: %w", err)
+		}
+		message.Infof("%s", strings.TrimSuffix(table.String(), "\n"))
+	}
+
+	return nil
+}
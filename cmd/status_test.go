@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opensourcecorp/vdm/internal/vdmspec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatus(t *testing.T) {
+	remoteURL, _ := newTestGitServer(t)
+	server := newTestFileServer(t)
+	chdirTemp(t)
+	resetSyncFlags(t)
+
+	gitRemote := vdmspec.Remote{Remote: remoteURL, Version: "main", LocalPath: "./deps/git"}
+	fileRemote := vdmspec.Remote{Type: vdmspec.FileType, Remote: server.URL + "/some.file", LocalPath: "./deps/some.file"}
+	orphan := vdmspec.Remote{Type: vdmspec.FileType, Remote: server.URL + "/orphan.file", LocalPath: "./deps/orphan.file"}
+	writeTestSpec(t, vdmspec.Spec{Remotes: []vdmspec.Remote{gitRemote, fileRemote, orphan}})
+	require.NoError(t, sync())
+
+	missing := vdmspec.Remote{Type: vdmspec.FileType, Remote: server.URL + "/missing.file", LocalPath: "./deps/missing.file"}
+	changedFile := fileRemote
+	changedFile.Remote = server.URL + "/changed.file"
+	spec := vdmspec.Spec{Remotes: []vdmspec.Remote{gitRemote, changedFile, missing}}
+	writeTestSpec(t, spec)
+
+	require.NoError(t, os.WriteFile(filepath.Join(gitRemote.LocalPath, "contents.txt"), []byte("edited"), 0644))
+
+	getReport := func(t *testing.T) statusReport {
+		state, err := vdmspec.GetStateFromFile(vdmspec.StateFilePath(RootFlagValues.SpecFilePath))
+		require.NoError(t, err)
+		lock, err := vdmspec.GetLockFromFile(vdmspec.LockFilePath(RootFlagValues.SpecFilePath))
+		require.NoError(t, err)
+
+		report, err := buildStatusReport(spec, state, lock, func(vdmspec.Remote) bool { return true })
+		require.NoError(t, err)
+		return report
+	}
+
+	t.Run("reports every state", func(t *testing.T) {
+		report := getReport(t)
+
+		var got []remoteState
+		for _, result := range report.Remotes {
+			got = append(got, result.State)
+		}
+		assert.Equal(t, []remoteState{stateDrifted, stateOutOfDate, stateMissing, stateOrphaned}, got)
+
+		assert.Equal(t, "git", report.Remotes[0].Name)
+		assert.Equal(t, vdmspec.GitType, report.Remotes[0].Type)
+		assert.Equal(t, "main", report.Remotes[0].InstalledVersion)
+		assert.Len(t, report.Remotes[0].Resolved, 40)
+		assert.Equal(t, "orphan.file", report.Remotes[3].Name)
+	})
+
+	t.Run("in sync after syncing again", func(t *testing.T) {
+		require.NoError(t, os.RemoveAll(gitRemote.LocalPath))
+		require.NoError(t, sync())
+
+		for _, result := range getReport(t).Remotes {
+			assert.Equal(t, stateInSync, result.State, result.Name)
+		}
+	})
+
+	t.Run("out of date when the installed commit isn't the locked one", func(t *testing.T) {
+		vdmMeta, err := gitRemote.GetVDMMeta()
+		require.NoError(t, err)
+		require.NotNil(t, vdmMeta.Git)
+		installed := vdmMeta.Git.Commit
+		other := strings.Repeat("0", 40)
+		vdmMeta.Git.Commit = other
+		require.NoError(t, vdmMeta.Write())
+		defer func() {
+			vdmMeta.Git.Commit = installed
+			require.NoError(t, vdmMeta.Write())
+		}()
+
+		report := getReport(t)
+		assert.Equal(t, stateOutOfDate, report.Remotes[0].State)
+		assert.Equal(t, other, report.Remotes[0].Resolved)
+	})
+
+	t.Run("doesn't change anything", func(t *testing.T) {
+		lockBefore, err := os.ReadFile(vdmspec.LockFileName)
+		require.NoError(t, err)
+
+		require.NoError(t, status(nil))
+		StatusFlagValues.Output = outputFormatJSON
+		defer func() { StatusFlagValues.Output = outputFormatText }()
+		require.NoError(t, status(nil))
+
+		lockAfter, err := os.ReadFile(vdmspec.LockFileName)
+		require.NoError(t, err)
+		assert.Equal(t, lockBefore, lockAfter)
+	})
+}
+
+func TestShortResolved(t *testing.T) {
+	assert.Equal(t, "2e6657f5ac01", shortResolved("2e6657f5ac013296167c4dd92fbb46f0e3dbdc5f"))
+	assert.Equal(t, "sha256:abcdef012345", shortResolved("sha256:abcdef0123456789"))
+	assert.Equal(t, "", shortResolved(""))
+}
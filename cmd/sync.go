@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"runtime"
 
 	"github.com/opensourcecorp/vdm/internal/message"
 	"github.com/opensourcecorp/vdm/internal/remotes"
@@ -10,22 +12,105 @@ import (
 )
 
 var syncCmd = &cobra.Command{
-	Use:   "sync",
+	Use:   "sync [remote...]",
 	Short: "Sync remotes based on specfile",
-	RunE:  syncExecute,
+	Long: `Sync remotes based on specfile. Remotes can be selected by their name, local
+path, or remote, and/or by --tag, in which case every other remote is left as it
+is; if none are selected, all remotes are synced.`,
+	RunE: syncExecute,
 }
 
-func syncExecute(_ *cobra.Command, _ []string) error {
+type syncFlags struct {
+	NoPrune    bool
+	Force      bool
+	KeepGitDir bool
+	Frozen     bool
+	DryRun     bool
+	Refresh    bool
+	Output     string
+	Jobs       int
+	Tags       []string
+}
+
+// SyncFlagValues contains an initalized [syncFlags] struct with populated
+// values.
+var SyncFlagValues syncFlags
+
+// Flag name keys
+const (
+	noPruneFlagKey    string = "no-prune"
+	forceFlagKey      string = "force"
+	keepGitDirFlagKey string = "keep-git-dir"
+	frozenFlagKey     string = "frozen"
+	dryRunFlagKey     string = "dry-run"
+	refreshFlagKey    string = "refresh"
+	outputFlagKey     string = "output"
+	jobsFlagKey       string = "jobs"
+	tagFlagKey        string = "tag"
+)
+
+func init() {
+	syncCmd.Flags().BoolVar(&SyncFlagValues.NoPrune, noPruneFlagKey, false, "Don't remove remotes that are no longer in the specfile")
+	syncCmd.Flags().BoolVar(&SyncFlagValues.Force, forceFlagKey, false, "Remove remotes that are no longer in the specfile, even if they have local edits")
+	syncCmd.Flags().BoolVar(&SyncFlagValues.KeepGitDir, keepGitDirFlagKey, false, "Keep the .git directory for all git remotes, regardless of their spec")
+	syncCmd.Flags().BoolVar(&SyncFlagValues.Frozen, frozenFlagKey, false, "Fail instead of syncing if the lockfile or anything on disk would change (for CI)")
+	syncCmd.Flags().BoolVar(&SyncFlagValues.DryRun, dryRunFlagKey, false, "Print what a sync would do, without retrieving or changing anything")
+	syncCmd.Flags().BoolVar(&SyncFlagValues.Refresh, refreshFlagKey, false, "Re-download file remotes to check them for upstream changes, instead of trusting the server to say whether they've changed")
+	syncCmd.Flags().StringVarP(&SyncFlagValues.Output, outputFlagKey, "o", outputFormatText, "Output format for --dry-run, one of 'text' or 'json'")
+	syncCmd.Flags().IntVarP(&SyncFlagValues.Jobs, jobsFlagKey, "j", runtime.NumCPU(), "Maximum number of remotes to sync at once")
+	syncCmd.Flags().StringSliceVar(&SyncFlagValues.Tags, tagFlagKey, nil, "Only sync remotes with this tag (can be repeated)")
+}
+
+func syncExecute(_ *cobra.Command, args []string) error {
 	MaybeSetDebug()
-	if err := sync(); err != nil {
+	if err := syncSelectors(args); err != nil {
 		return fmt.Errorf("executing sync command: %w", err)
 	}
 	return nil
 }
 
 // sync does the heavy lifting to ensure that the local directory tree(s) match
-// the desired state as defined in the specfile.
+// the desired state as defined in the specfile, honoring the lockfile.
 func sync() error {
+	return syncWithUpdates(nil)
+}
+
+// syncSelectors is [sync], except that only the remotes selected by the
+// provided selectors and --tag flags are synced (see [selectRemotes]).
+func syncSelectors(selectors []string) error {
+	if len(selectors) == 0 && len(SyncFlagValues.Tags) == 0 {
+		return sync()
+	}
+
+	spec, err := vdmspec.GetSpecFromFile(RootFlagValues.SpecFilePath)
+	if err != nil {
+		return fmt.Errorf("getting specs from spec file: %w", err)
+	}
+
+	isSelected, err := selectRemotes(spec, selectors, SyncFlagValues.Tags)
+	if err != nil {
+		return err
+	}
+
+	return syncSelected(isSelected, nil)
+}
+
+// syncWithUpdates is [sync], except that any remotes for which shouldUpdate
+// returns true are re-resolved from their remotes instead of honoring what's in
+// the lockfile.
+func syncWithUpdates(shouldUpdate func(vdmspec.Remote) bool) error {
+	return syncSelected(nil, shouldUpdate)
+}
+
+// syncSelected is [syncWithUpdates], except that only the remotes for which
+// isSelected returns true are synced (or pruned, if they were removed from the
+// spec), and every other remote is left as it is. A nil isSelected selects
+// every remote.
+func syncSelected(isSelected, shouldUpdate func(vdmspec.Remote) bool) error {
+	if err := checkOutputFormat(SyncFlagValues.Output); err != nil {
+		return err
+	}
+
 	spec, err := vdmspec.GetSpecFromFile(RootFlagValues.SpecFilePath)
 	if err != nil {
 		return fmt.Errorf("getting specs from spec file: %w", err)
@@ -36,47 +121,295 @@ func sync() error {
 		return fmt.Errorf("your vdm spec file is malformed: %w", err)
 	}
 
-SpecLoop:
-	for _, remote := range spec.Remotes {
-		// process stored vdm metafile so we know what operations to actually
-		// perform for existing directories
-		vdmMeta, err := remote.GetVDMMeta()
-		if err != nil {
-			return fmt.Errorf("getting vdm metadata file for sync: %w", err)
-		}
+	if SyncFlagValues.Jobs < 1 {
+		return fmt.Errorf("--%s must be at least 1, but was %d", jobsFlagKey, SyncFlagValues.Jobs)
+	}
 
-		if vdmMeta == (vdmspec.Remote{}) {
-			message.Infof("%s: %s not found at local path, will be created", remote.OpMsg(), vdmspec.MetaFileName)
-		} else {
-			if vdmMeta.Version != remote.Version && vdmMeta.Remote != remote.Remote {
-				message.Infof("%s: Will change '%s' from current local version spec '%s' to '%s'...", remote.OpMsg(), remote.Remote, vdmMeta.Version, remote.Version)
-				panic("not implemented")
+	if SyncFlagValues.KeepGitDir {
+		for i := range spec.Remotes {
+			if spec.Remotes[i].Subdir != "" || spec.Remotes[i].HasFilters() {
+				message.Debugf("%s: not keeping .git dir, since only part of the remote is retrieved", spec.Remotes[i].OpMsg())
+				continue
+			}
+			if spec.Remotes[i].Type == vdmspec.GitType || spec.Remotes[i].Type == "" {
+				spec.Remotes[i].KeepGitDir = true
 			}
-			message.Infof("%s: version unchanged in spec file, skipping", remote.OpMsg())
-			continue SpecLoop
 		}
+	}
+
+	stateFilePath := vdmspec.StateFilePath(RootFlagValues.SpecFilePath)
+	state, err := vdmspec.GetStateFromFile(stateFilePath)
+	if err != nil {
+		return fmt.Errorf("getting vdm state: %w", err)
+	}
+
+	lockFilePath := vdmspec.LockFilePath(RootFlagValues.SpecFilePath)
+	lock, err := vdmspec.GetLockFromFile(lockFilePath)
+	if err != nil {
+		return fmt.Errorf("getting vdm lockfile: %w", err)
+	}
+
+	plan, err := planSync(spec, state, lock, shouldUpdate)
+	if err != nil {
+		return fmt.Errorf("planning sync: %w", err)
+	}
+	if isSelected != nil {
+		plan = plan.only(isSelected)
+	}
+
+	if SyncFlagValues.Frozen {
+		return checkFrozen(spec, lock, plan)
+	}
+
+	if SyncFlagValues.DryRun {
+		message.Debugf("--%s was set, so only printing the plan", dryRunFlagKey)
+		return plan.print(SyncFlagValues.Output)
+	}
+
+	// Track everything in the spec before touching the disk, so that even a
+	// failed sync can't lose track of a path that vdm wrote to
+	state = state.Track(spec.Remotes...)
+	if err := state.WriteToFile(stateFilePath); err != nil {
+		return fmt.Errorf("could not write %s file to disk: %w", vdmspec.StateFileName, err)
+	}
+
+	newLock, untracked, err := applyPlan(plan)
+	if err != nil {
+		return err
+	}
+
+	if err := newLock.WriteToFile(lockFilePath); err != nil {
+		return fmt.Errorf("could not write %s file to disk: %w", vdmspec.LockFileName, err)
+	}
+
+	state = state.Untrack(untracked...)
+	if err := state.WriteToFile(stateFilePath); err != nil {
+		return fmt.Errorf("could not write %s file to disk: %w", vdmspec.StateFileName, err)
+	}
+
+	message.Infof("All done!")
+	return nil
+}
+
+// opResult is the outcome of preparing a single planned operation.
+type opResult struct {
+	index  int
+	locked vdmspec.LockedRemote
+	// fileMeta is what the server said about a retrieved file remote, to be
+	// written to its metafile.
+	fileMeta *vdmspec.FileMeta
+	staged   *remotes.Staged
+	err      error
+}
+
+// applyPlan performs each operation in the plan as a single transaction: either
+// every remote is synced, or the tree is left exactly as it was.
+//
+// First, each remote is retrieved into a staging directory, running up to the
+// number of jobs set by the --jobs flag at once, with each operation's output
+// printed together once it finishes. A failed retrieval doesn't stop the
+// others, and all failures are returned together. Only once every retrieval has
+// succeeded are the staged remotes swapped into place (and pruned remotes moved
+// out of it), and if any of those swaps fail, every swap is rolled back.
+//
+// It returns the new lockfile contents along with any remotes that should no
+// longer be tracked. The new lockfile only has entries for what's currently in
+// the spec, so removed remotes fall out of it.
+func applyPlan(plan syncPlan) (vdmspec.Lock, []vdmspec.Remote, error) {
+	jobs := SyncFlagValues.Jobs
+	message.Debugf("preparing %d operation(s) with up to %d job(s) at once", len(plan.Operations), jobs)
 
-		switch remote.Type {
-		case vdmspec.GitType, "":
-			if err := remotes.SyncGit(remote); err != nil {
-				return fmt.Errorf("syncing git remote: %w", err)
+	semaphore := make(chan struct{}, jobs)
+	resultsChan := make(chan opResult, len(plan.Operations))
+	for i, op := range plan.Operations {
+		go func(i int, op plannedOp) {
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			out := message.NewGroup()
+			result := prepareOp(op, out)
+			result.index = i
+			if result.err != nil {
+				out.Errorf("%s: %s", op.Remote.OpMsg(), result.err.Error())
 			}
-		case vdmspec.FileType:
-			if err := remotes.SyncFile(remote); err != nil {
-				return fmt.Errorf("syncing file remote: %w", err)
+			out.Flush()
+
+			resultsChan <- result
+		}(i, op)
+	}
+
+	results := make([]opResult, len(plan.Operations))
+	for range plan.Operations {
+		result := <-resultsChan
+		results[result.index] = result
+	}
+
+	// Whatever happens from here, the staging directories need to go
+	defer func() {
+		for _, result := range results {
+			if result.staged == nil {
+				continue
 			}
-		default:
-			return fmt.Errorf("unrecognized remote type '%s'", remote.Type)
+			if err := result.staged.Cleanup(); err != nil {
+				message.Warnf("%s", err.Error())
+			}
+		}
+	}()
+
+	var allErrors []error
+	for i, result := range results {
+		if result.err != nil {
+			allErrors = append(allErrors, fmt.Errorf("%s: %w", plan.Operations[i].Remote.OpMsg(), result.err))
 		}
+	}
+	if len(allErrors) > 0 {
+		return vdmspec.Lock{}, nil, fmt.Errorf("%d remote(s) failed to sync, so nothing was changed: %w", len(allErrors), errors.Join(allErrors...))
+	}
+
+	if err := installAll(plan, results); err != nil {
+		return vdmspec.Lock{}, nil, err
+	}
 
-		err = remote.WriteVDMMeta()
+	var (
+		newLock   vdmspec.Lock
+		untracked []vdmspec.Remote
+	)
+	for i, result := range results {
+		op := plan.Operations[i]
+		if op.Action == actionPrune || op.Action == actionUntrack {
+			untracked = append(untracked, op.Remote)
+		}
+		if !result.locked.IsZero() {
+			newLock.Remotes = append(newLock.Remotes, result.locked)
+		}
+	}
+
+	return newLock, untracked, nil
+}
+
+// prepareOp does everything for a single planned operation that can be done
+// without touching the remote's local path, printing its output to the
+// provided group.
+func prepareOp(op plannedOp, out *message.Group) opResult {
+	remote := op.Remote
+
+	switch op.Action {
+	case actionSkip:
+		out.Infof("%s: %s, skipping", remote.OpMsg(), op.Reason)
+		return opResult{locked: op.locked}
+
+	case actionUntrack:
+		out.Warnf("%s: %s", remote.OpMsg(), op.Reason)
+		return opResult{}
+
+	case actionPrune:
+		out.Infof("%s: %s, will be removed", remote.OpMsg(), op.Reason)
+		staged, err := remotes.StageRemoval(remote, out)
 		if err != nil {
-			return fmt.Errorf("could not write %s file to disk: %w", vdmspec.MetaFileName, err)
+			return opResult{err: fmt.Errorf("staging removal: %w", err)}
 		}
+		return opResult{staged: staged}
 
-		message.Infof("%s: Done.", remote.OpMsg())
+	case actionUpdate, actionRevalidate:
+		out.Infof("%s: %s...", remote.OpMsg(), op.Reason)
+
+	case actionCreate:
+		out.Infof("%s: %s, will be created", remote.OpMsg(), op.Reason)
+	}
+
+	staged, err := remotes.Stage(remote, out)
+	if err != nil {
+		return opResult{err: fmt.Errorf("staging remote: %w", err)}
+	}
+
+	var (
+		resolved vdmspec.LockedRemote
+		fileMeta *vdmspec.FileMeta
+	)
+	switch remote.Type {
+	case vdmspec.GitType, "":
+		resolved, err = remotes.SyncGit(staged.StagedRemote(), op.locked, out)
+		if err != nil {
+			err = fmt.Errorf("syncing git remote: %w", err)
+		}
+	case vdmspec.FileType:
+		previous := op.fileMeta
+		if previous != nil && SyncFlagValues.Refresh {
+			out.Debugf("%s: --%s was set, so not making a conditional request", remote.OpMsg(), refreshFlagKey)
+			previous = &vdmspec.FileMeta{SHA256: previous.SHA256}
+		}
+		var result remotes.FileResult
+		result, err = remotes.SyncFile(staged.StagedRemote(), op.locked, previous, out)
+		if err != nil {
+			err = fmt.Errorf("syncing file remote: %w", err)
+			break
+		}
+		if result.Unchanged {
+			out.Infof("%s: nothing to replace, skipping", remote.OpMsg())
+			if err := staged.Cleanup(); err != nil {
+				out.Warnf("%s", err.Error())
+			}
+			return opResult{locked: result.Locked}
+		}
+		resolved, fileMeta = result.Locked, &result.Meta
+	default:
+		err = fmt.Errorf("unrecognized remote type '%s'", remote.Type)
+	}
+	if err != nil {
+		return opResult{staged: staged, err: err}
+	}
+
+	// What was resolved was the staged copy, but what's locked should be the
+	// remote as it's specified
+	resolved.Remote = remote
+	out.Infof("%s: Retrieved.", remote.OpMsg())
+
+	return opResult{locked: resolved, fileMeta: fileMeta, staged: staged}
+}
+
+// installAll swaps every staged remote into place, in plan order, and writes
+// their metafiles. If anything fails, everything that was already swapped is
+// rolled back.
+func installAll(plan syncPlan, results []opResult) error {
+	var installed []*remotes.Staged
+
+	rollback := func(cause error) error {
+		allErrors := []error{cause}
+		for i := len(installed) - 1; i >= 0; i-- {
+			if err := installed[i].Rollback(nil); err != nil {
+				allErrors = append(allErrors, fmt.Errorf("rolling back %s: %w", installed[i].Remote.OpMsg(), err))
+			}
+		}
+		return fmt.Errorf("installing synced remotes failed, so all changes were rolled back: %w", errors.Join(allErrors...))
+	}
+
+	for i, result := range results {
+		if result.staged == nil {
+			continue
+		}
+		op := plan.Operations[i]
+
+		// Track before installing, since a partial install still needs to be
+		// rolled back
+		installed = append(installed, result.staged)
+		if err := result.staged.Install(op.Previous, nil); err != nil {
+			return rollback(fmt.Errorf("%s: %w", op.Remote.OpMsg(), err))
+		}
+
+		if op.Action == actionPrune {
+			message.Infof("%s: Removed.", op.Remote.OpMsg())
+			continue
+		}
+
+		vdmMeta := vdmspec.VDMMeta{Remote: op.Remote, File: result.fileMeta}
+		if result.locked.Commit != "" {
+			vdmMeta.Git = &vdmspec.GitMeta{Commit: result.locked.Commit, Tag: result.locked.Tag}
+		}
+		if err := vdmMeta.Write(); err != nil {
+			return rollback(fmt.Errorf("%s: could not write %s file to disk: %w", op.Remote.OpMsg(), vdmspec.MetaFileName, err))
+		}
+		message.Infof("%s: Done.", op.Remote.OpMsg())
 	}
 
-	message.Infof("All done!")
 	return nil
 }
@@ -1,12 +1,19 @@
 package cmd
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/opensourcecorp/vdm/internal/vdmspec"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
 )
 
 const testVDMRoot = "../testdata"
@@ -65,3 +72,618 @@ func TestSync(t *testing.T) {
 		})
 	})
 }
+
+// chdirTemp moves the test into a fresh temporary directory, so that relative
+// local paths in test specs don't leak into the source tree.
+func chdirTemp(t *testing.T) string {
+	t.Helper()
+
+	oldWD, err := os.Getwd()
+	require.NoError(t, err)
+
+	tempDir := t.TempDir()
+	require.NoError(t, os.Chdir(tempDir))
+	t.Cleanup(func() {
+		require.NoError(t, os.Chdir(oldWD))
+	})
+
+	return tempDir
+}
+
+// resetSyncFlags puts the sync flags back to their defaults, both now and when
+// the test is done.
+func resetSyncFlags(t *testing.T) {
+	t.Helper()
+
+	defaults := syncFlags{Output: outputFormatText, Jobs: runtime.NumCPU()}
+	SyncFlagValues = defaults
+	t.Cleanup(func() { SyncFlagValues = defaults })
+}
+
+// writeTestSpec writes the provided spec to disk, and points vdm at it.
+func writeTestSpec(t *testing.T, spec vdmspec.Spec) {
+	t.Helper()
+
+	specContents, err := yaml.Marshal(spec)
+	require.NoError(t, err)
+
+	specFilePath := "./vdm.yaml"
+	require.NoError(t, os.WriteFile(specFilePath, specContents, 0644))
+	RootFlagValues.SpecFilePath = specFilePath
+}
+
+func TestSyncChangedRemote(t *testing.T) {
+	server := newTestFileServer(t)
+	chdirTemp(t)
+
+	remote := vdmspec.Remote{
+		Type:      vdmspec.FileType,
+		Remote:    server.URL + "/v1/some.file",
+		LocalPath: "./deps/some.file",
+	}
+	writeTestSpec(t, vdmspec.Spec{Remotes: []vdmspec.Remote{remote}})
+	require.NoError(t, sync())
+
+	got, err := os.ReadFile(remote.LocalPath)
+	require.NoError(t, err)
+	assert.Equal(t, "content from /v1/some.file", string(got))
+
+	t.Run("changed remote is re-retrieved", func(t *testing.T) {
+		remote.Remote = server.URL + "/v2/some.file"
+		writeTestSpec(t, vdmspec.Spec{Remotes: []vdmspec.Remote{remote}})
+		require.NoError(t, sync())
+
+		got, err := os.ReadFile(remote.LocalPath)
+		require.NoError(t, err)
+		assert.Equal(t, "content from /v2/some.file", string(got))
+
+		vdmMeta, err := remote.GetVDMMeta()
+		require.NoError(t, err)
+		assert.Equal(t, remote, vdmMeta.Remote)
+	})
+}
+
+func TestSyncKeepGitDirToggle(t *testing.T) {
+	remoteURL, _ := newTestGitServer(t)
+	chdirTemp(t)
+	resetSyncFlags(t)
+
+	remote := vdmspec.Remote{Remote: remoteURL, Version: "main", LocalPath: "./deps/git"}
+	writeTestSpec(t, vdmspec.Spec{Remotes: []vdmspec.Remote{remote}})
+
+	SyncFlagValues.KeepGitDir = true
+	require.NoError(t, sync())
+	require.DirExists(t, filepath.Join(remote.LocalPath, ".git"))
+
+	// Stands in for unpushed work in the kept clone
+	localWork := filepath.Join(remote.LocalPath, "local-work.txt")
+	require.NoError(t, os.WriteFile(localWork, []byte("local work"), 0644))
+
+	t.Run("syncing without --keep-git-dir leaves the kept clone alone", func(t *testing.T) {
+		SyncFlagValues.KeepGitDir = false
+		require.NoError(t, sync())
+
+		assert.DirExists(t, filepath.Join(remote.LocalPath, ".git"))
+		assert.FileExists(t, localWork)
+	})
+}
+
+func TestSyncKeepGitDirTurnedOn(t *testing.T) {
+	remoteURL, _ := newTestGitServer(t)
+	chdirTemp(t)
+	resetSyncFlags(t)
+
+	remote := vdmspec.Remote{Remote: remoteURL, Version: "main", LocalPath: "./deps/git"}
+	writeTestSpec(t, vdmspec.Spec{Remotes: []vdmspec.Remote{remote}})
+	require.NoError(t, sync())
+	require.NoDirExists(t, filepath.Join(remote.LocalPath, ".git"))
+
+	lockBefore, err := vdmspec.GetLockFromFile(vdmspec.LockFilePath(RootFlagValues.SpecFilePath))
+	require.NoError(t, err)
+
+	t.Run("setting keep_git_dir on a synced remote keeps a clone", func(t *testing.T) {
+		remote.KeepGitDir = true
+		writeTestSpec(t, vdmspec.Spec{Remotes: []vdmspec.Remote{remote}})
+		require.NoError(t, sync())
+
+		assert.DirExists(t, filepath.Join(remote.LocalPath, ".git"))
+
+		lock, err := vdmspec.GetLockFromFile(vdmspec.LockFilePath(RootFlagValues.SpecFilePath))
+		require.NoError(t, err)
+		require.Len(t, lock.Remotes, 1)
+		assert.Equal(t, lockBefore.Remotes[0].Commit, lock.Remotes[0].Commit)
+	})
+
+	t.Run("and syncing again leaves it alone", func(t *testing.T) {
+		lock, err := vdmspec.GetLockFromFile(vdmspec.LockFilePath(RootFlagValues.SpecFilePath))
+		require.NoError(t, err)
+		state, err := vdmspec.GetStateFromFile(vdmspec.StateFilePath(RootFlagValues.SpecFilePath))
+		require.NoError(t, err)
+
+		plan, err := planSync(vdmspec.Spec{Remotes: []vdmspec.Remote{remote}}, state, lock, nil)
+		require.NoError(t, err)
+		require.Len(t, plan.Operations, 1)
+		assert.Equal(t, actionSkip, plan.Operations[0].Action)
+	})
+}
+
+// newTestFileServer returns a server that responds to every request with the
+// requested path as its content.
+func newTestFileServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte("content from " + r.URL.Path))
+		require.NoError(t, err)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestSyncFileRevalidation(t *testing.T) {
+	contents := "first"
+	var conditional, sent int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := `"` + contents + `"`
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") != "" {
+			conditional++
+		}
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		sent++
+		_, err := w.Write([]byte(contents))
+		require.NoError(t, err)
+	}))
+	t.Cleanup(server.Close)
+	chdirTemp(t)
+	resetSyncFlags(t)
+	resetUpdateFlags(t)
+
+	remote := vdmspec.Remote{Type: vdmspec.FileType, Remote: server.URL + "/some.file", LocalPath: "./deps/some.file"}
+	writeTestSpec(t, vdmspec.Spec{Remotes: []vdmspec.Remote{remote}})
+	lockFilePath := vdmspec.LockFilePath(RootFlagValues.SpecFilePath)
+	require.NoError(t, sync())
+
+	firstLock, err := vdmspec.GetLockFromFile(lockFilePath)
+	require.NoError(t, err)
+
+	t.Run("metafile records what the server said", func(t *testing.T) {
+		vdmMeta, err := remote.GetVDMMeta()
+		require.NoError(t, err)
+		require.NotNil(t, vdmMeta.File)
+		assert.Equal(t, `"first"`, vdmMeta.File.ETag)
+		assert.Equal(t, firstLock.Remotes[0].SHA256, vdmMeta.File.SHA256)
+	})
+
+	t.Run("unchanged file is only revalidated", func(t *testing.T) {
+		require.NoError(t, sync())
+		assert.Equal(t, 1, conditional)
+		assert.Equal(t, 1, sent)
+
+		lock, err := vdmspec.GetLockFromFile(lockFilePath)
+		require.NoError(t, err)
+		assert.Equal(t, firstLock, lock)
+	})
+
+	t.Run("changed file fails the sync", func(t *testing.T) {
+		contents = "second"
+		err := sync()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "vdm update")
+		assert.Equal(t, 2, sent)
+
+		got, err := os.ReadFile(remote.LocalPath)
+		require.NoError(t, err)
+		assert.Equal(t, "first", string(got))
+
+		lock, err := vdmspec.GetLockFromFile(lockFilePath)
+		require.NoError(t, err)
+		assert.Equal(t, firstLock, lock)
+	})
+
+	t.Run("changed file is replaced and relocked by update", func(t *testing.T) {
+		require.NoError(t, update([]string{remote.LocalPath}))
+		assert.Equal(t, 3, sent)
+
+		got, err := os.ReadFile(remote.LocalPath)
+		require.NoError(t, err)
+		assert.Equal(t, "second", string(got))
+
+		lock, err := vdmspec.GetLockFromFile(lockFilePath)
+		require.NoError(t, err)
+		assert.NotEqual(t, firstLock.Remotes[0].SHA256, lock.Remotes[0].SHA256)
+	})
+
+	t.Run("--refresh skips the conditional request", func(t *testing.T) {
+		SyncFlagValues.Refresh = true
+		defer resetSyncFlags(t)
+
+		conditionalBefore := conditional
+		require.NoError(t, sync())
+		assert.Equal(t, conditionalBefore, conditional)
+		assert.Equal(t, 4, sent)
+	})
+}
+
+func TestSyncPrune(t *testing.T) {
+	server := newTestFileServer(t)
+
+	setup := func(t *testing.T) (kept vdmspec.Remote, removed vdmspec.Remote) {
+		chdirTemp(t)
+		resetSyncFlags(t)
+
+		kept = vdmspec.Remote{
+			Type:      vdmspec.FileType,
+			Remote:    server.URL + "/kept.file",
+			LocalPath: "./deps/kept.file",
+		}
+		removed = vdmspec.Remote{
+			Type:      vdmspec.FileType,
+			Remote:    server.URL + "/removed.file",
+			LocalPath: "./deps/removed.file",
+		}
+		writeTestSpec(t, vdmspec.Spec{Remotes: []vdmspec.Remote{kept, removed}})
+		require.NoError(t, sync())
+
+		writeTestSpec(t, vdmspec.Spec{Remotes: []vdmspec.Remote{kept}})
+		return kept, removed
+	}
+
+	t.Run("removed remote is pruned", func(t *testing.T) {
+		kept, removed := setup(t)
+		require.NoError(t, sync())
+
+		_, err := os.Stat(removed.LocalPath)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+		_, err = os.Stat(removed.MakeMetaFilePath())
+		assert.ErrorIs(t, err, os.ErrNotExist)
+		_, err = os.Stat(kept.LocalPath)
+		assert.NoError(t, err)
+
+		state, err := vdmspec.GetStateFromFile(vdmspec.StateFilePath(RootFlagValues.SpecFilePath))
+		require.NoError(t, err)
+		assert.Equal(t, []vdmspec.Remote{kept}, state.Remotes)
+	})
+
+	t.Run("--no-prune leaves removed remote alone", func(t *testing.T) {
+		_, removed := setup(t)
+		SyncFlagValues.NoPrune = true
+		require.NoError(t, sync())
+
+		_, err := os.Stat(removed.LocalPath)
+		assert.NoError(t, err)
+	})
+
+	t.Run("removed remote with local edits is not pruned", func(t *testing.T) {
+		_, removed := setup(t)
+		require.NoError(t, os.WriteFile(removed.LocalPath, []byte("my edits"), 0644))
+		require.NoError(t, sync())
+
+		_, err := os.Stat(removed.LocalPath)
+		assert.NoError(t, err)
+
+		state, err := vdmspec.GetStateFromFile(vdmspec.StateFilePath(RootFlagValues.SpecFilePath))
+		require.NoError(t, err)
+		assert.Contains(t, state.Remotes, removed)
+
+		t.Run("unless forced", func(t *testing.T) {
+			SyncFlagValues.Force = true
+			require.NoError(t, sync())
+
+			_, err := os.Stat(removed.LocalPath)
+			assert.ErrorIs(t, err, os.ErrNotExist)
+		})
+	})
+}
+
+func TestSyncSelectors(t *testing.T) {
+	server := newTestFileServer(t)
+
+	first := vdmspec.Remote{Name: "first", Type: vdmspec.FileType, Remote: server.URL + "/first.file", LocalPath: "./deps/first.file", Tags: []string{"odd"}}
+	second := vdmspec.Remote{Type: vdmspec.FileType, Remote: server.URL + "/second.file", LocalPath: "./deps/second.file"}
+	third := vdmspec.Remote{Type: vdmspec.FileType, Remote: server.URL + "/third.file", LocalPath: "./deps/third.file", Tags: []string{"odd"}}
+
+	setup := func(t *testing.T) {
+		chdirTemp(t)
+		resetSyncFlags(t)
+		writeTestSpec(t, vdmspec.Spec{Remotes: []vdmspec.Remote{first, second, third}})
+	}
+	synced := func(t *testing.T) []string {
+		t.Helper()
+
+		var names []string
+		for _, remote := range []vdmspec.Remote{first, second, third} {
+			if _, err := os.Stat(remote.LocalPath); err == nil {
+				names = append(names, remote.DisplayName())
+			}
+		}
+		return names
+	}
+
+	t.Run("by name", func(t *testing.T) {
+		setup(t)
+		require.NoError(t, syncSelectors([]string{"first", "second.file"}))
+		assert.Equal(t, []string{"first", "second.file"}, synced(t))
+
+		lock, err := vdmspec.GetLockFromFile(vdmspec.LockFilePath(RootFlagValues.SpecFilePath))
+		require.NoError(t, err)
+		assert.Len(t, lock.Remotes, 2)
+	})
+
+	t.Run("by tag", func(t *testing.T) {
+		setup(t)
+		SyncFlagValues.Tags = []string{"odd"}
+		require.NoError(t, syncSelectors(nil))
+		assert.Equal(t, []string{"first", "third.file"}, synced(t))
+	})
+
+	t.Run("unknown selector is an error", func(t *testing.T) {
+		setup(t)
+		assert.Error(t, syncSelectors([]string{"fourth"}))
+		assert.Empty(t, synced(t))
+	})
+}
+
+// newTestGitServer serves a new local git repository over git's "dumb" HTTP
+// protocol, so that it passes spec validation as a remote. The repository has a
+// 'contents.txt' file on its 'main' branch. Call the returned function to
+// commit new contents to it, tagging the new commit with any provided tags.
+func newTestGitServer(t *testing.T) (string, func(contents string, tags ...string) string) {
+	t.Helper()
+
+	repoPath := t.TempDir()
+	git := func(args ...string) string {
+		args = append([]string{"-C", repoPath, "-c", "user.name=vdm", "-c", "user.email=vdm@example.com"}, args...)
+		output, err := exec.Command("git", args...).CombinedOutput()
+		require.NoError(t, err, string(output))
+		return strings.TrimSpace(string(output))
+	}
+
+	commit := func(contents string, tags ...string) string {
+		require.NoError(t, os.WriteFile(filepath.Join(repoPath, "contents.txt"), []byte(contents), 0644))
+		git("add", "contents.txt")
+		git("commit", "-m", contents)
+		for _, tag := range tags {
+			git("tag", tag)
+		}
+		git("update-server-info")
+		return git("rev-parse", "HEAD")
+	}
+
+	git("init", "--initial-branch=main")
+	commit("first")
+
+	server := httptest.NewServer(http.FileServer(http.Dir(filepath.Join(repoPath, ".git"))))
+	t.Cleanup(server.Close)
+
+	return server.URL + "/", commit
+}
+
+func TestSyncLock(t *testing.T) {
+	remoteURL, commit := newTestGitServer(t)
+	chdirTemp(t)
+
+	remote := vdmspec.Remote{
+		Remote:    remoteURL,
+		Version:   "main",
+		LocalPath: "./deps/locked",
+	}
+	writeTestSpec(t, vdmspec.Spec{Remotes: []vdmspec.Remote{remote}})
+	lockFilePath := vdmspec.LockFilePath(RootFlagValues.SpecFilePath)
+
+	firstCommit, err := exec.Command("git", "ls-remote", remoteURL, "main").Output()
+	require.NoError(t, err)
+
+	require.NoError(t, sync())
+
+	t.Run("lockfile records resolved commit", func(t *testing.T) {
+		lock, err := vdmspec.GetLockFromFile(lockFilePath)
+		require.NoError(t, err)
+		require.Len(t, lock.Remotes, 1)
+		assert.Equal(t, strings.Fields(string(firstCommit))[0], lock.Remotes[0].Commit)
+	})
+
+	secondCommit := commit("second")
+
+	t.Run("sync honors the lockfile after the branch moves", func(t *testing.T) {
+		require.NoError(t, os.RemoveAll(remote.LocalPath))
+		require.NoError(t, sync())
+
+		got, err := os.ReadFile(filepath.Join(remote.LocalPath, "contents.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "first", string(got))
+	})
+
+	t.Run("update re-resolves and rewrites the lockfile", func(t *testing.T) {
+		require.NoError(t, update([]string{"deps/locked"}))
+
+		got, err := os.ReadFile(filepath.Join(remote.LocalPath, "contents.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "second", string(got))
+
+		lock, err := vdmspec.GetLockFromFile(lockFilePath)
+		require.NoError(t, err)
+		require.Len(t, lock.Remotes, 1)
+		assert.Equal(t, secondCommit, lock.Remotes[0].Commit)
+	})
+
+	t.Run("update fails on unknown remotes", func(t *testing.T) {
+		assert.Error(t, update([]string{"./deps/nope"}))
+	})
+}
+
+func TestSyncVersionConstraint(t *testing.T) {
+	remoteURL, commit := newTestGitServer(t)
+	firstCommit := commit("v1.0.0", "v1.0.0")
+	chdirTemp(t)
+	resetSyncFlags(t)
+
+	remote := vdmspec.Remote{Remote: remoteURL, Version: "^1.0", LocalPath: "./deps/constrained"}
+	writeTestSpec(t, vdmspec.Spec{Remotes: []vdmspec.Remote{remote}})
+	lockFilePath := vdmspec.LockFilePath(RootFlagValues.SpecFilePath)
+
+	requireResolved := func(t *testing.T, tag, commit string) {
+		t.Helper()
+
+		lock, err := vdmspec.GetLockFromFile(lockFilePath)
+		require.NoError(t, err)
+		require.Len(t, lock.Remotes, 1)
+		assert.Equal(t, tag, lock.Remotes[0].Tag)
+		assert.Equal(t, commit, lock.Remotes[0].Commit)
+
+		vdmMeta, err := remote.GetVDMMeta()
+		require.NoError(t, err)
+		require.NotNil(t, vdmMeta.Git)
+		assert.Equal(t, tag, vdmMeta.Git.Tag)
+		assert.Equal(t, commit, vdmMeta.Git.Commit)
+		assert.Equal(t, "^1.0", vdmMeta.Version)
+
+		got, err := os.ReadFile(filepath.Join(remote.LocalPath, "contents.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, tag, string(got))
+	}
+
+	require.NoError(t, sync())
+
+	t.Run("resolved tag & commit are recorded", func(t *testing.T) {
+		requireResolved(t, "v1.0.0", firstCommit)
+	})
+
+	secondCommit := commit("v1.1.0", "v1.1.0")
+	commit("v2.0.0", "v2.0.0")
+
+	t.Run("sync honors the lockfile after newer tags appear", func(t *testing.T) {
+		require.NoError(t, os.RemoveAll(remote.LocalPath))
+		require.NoError(t, sync())
+		requireResolved(t, "v1.0.0", firstCommit)
+	})
+
+	t.Run("outdated reports the newest matching tag", func(t *testing.T) {
+		lock, err := vdmspec.GetLockFromFile(lockFilePath)
+		require.NoError(t, err)
+
+		result, err := checkOutdated(remote, lock)
+		require.NoError(t, err)
+		assert.Equal(t, versionKindConstraint, result.Kind)
+		assert.Equal(t, "v1.0.0", result.LockedTag)
+		assert.Equal(t, "v1.1.0", result.Latest)
+		assert.True(t, result.Outdated)
+	})
+
+	t.Run("update re-resolves within the constraint", func(t *testing.T) {
+		require.NoError(t, update(nil))
+		requireResolved(t, "v1.1.0", secondCommit)
+	})
+}
+
+func TestSyncParallel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/missing") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, err := w.Write([]byte("content from " + r.URL.Path))
+		require.NoError(t, err)
+	}))
+	defer server.Close()
+
+	makeRemotes := func(names ...string) []vdmspec.Remote {
+		var remotes []vdmspec.Remote
+		for _, name := range names {
+			remotes = append(remotes, vdmspec.Remote{
+				Type:      vdmspec.FileType,
+				Remote:    server.URL + "/" + name,
+				LocalPath: "./deps/" + name,
+			})
+		}
+		return remotes
+	}
+
+	t.Run("all remotes are synced", func(t *testing.T) {
+		chdirTemp(t)
+		resetSyncFlags(t)
+		SyncFlagValues.Jobs = 2
+
+		remotes := makeRemotes("a", "b", "c", "d", "e")
+		writeTestSpec(t, vdmspec.Spec{Remotes: remotes})
+		require.NoError(t, sync())
+
+		for _, remote := range remotes {
+			got, err := os.ReadFile(remote.LocalPath)
+			require.NoError(t, err)
+			assert.Equal(t, "content from /"+filepath.Base(remote.LocalPath), string(got))
+		}
+
+		lock, err := vdmspec.GetLockFromFile(vdmspec.LockFilePath(RootFlagValues.SpecFilePath))
+		require.NoError(t, err)
+		require.Len(t, lock.Remotes, len(remotes))
+		for i, locked := range lock.Remotes {
+			assert.Equal(t, remotes[i], locked.Remote)
+		}
+	})
+
+	t.Run("failures are all reported, and nothing is changed", func(t *testing.T) {
+		chdirTemp(t)
+		resetSyncFlags(t)
+
+		remotes := makeRemotes("a", "missing-1", "b", "missing-2")
+		writeTestSpec(t, vdmspec.Spec{Remotes: remotes})
+		err := sync()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "missing-1")
+		assert.Contains(t, err.Error(), "missing-2")
+
+		entries, err := os.ReadDir("./deps")
+		require.NoError(t, err)
+		assert.Empty(t, entries, "no content or staging directories should be left behind")
+	})
+
+	t.Run("previous content is kept when any remote fails", func(t *testing.T) {
+		chdirTemp(t)
+		resetSyncFlags(t)
+
+		remotes := makeRemotes("a", "b")
+		writeTestSpec(t, vdmspec.Spec{Remotes: remotes})
+		require.NoError(t, sync())
+
+		changed := makeRemotes("a", "b")
+		changed[0].Remote = server.URL + "/a-v2"
+		changed[1].Remote = server.URL + "/missing-b"
+		writeTestSpec(t, vdmspec.Spec{Remotes: changed})
+		require.Error(t, sync())
+
+		got, err := os.ReadFile(remotes[0].LocalPath)
+		require.NoError(t, err)
+		assert.Equal(t, "content from /a", string(got))
+
+		vdmMeta, err := remotes[0].GetVDMMeta()
+		require.NoError(t, err)
+		assert.Equal(t, remotes[0], vdmMeta.Remote)
+	})
+
+	t.Run("overlapping local paths are rejected", func(t *testing.T) {
+		chdirTemp(t)
+		resetSyncFlags(t)
+
+		remotes := makeRemotes("a", "b")
+		remotes[1].LocalPath = "./deps/a/b"
+		writeTestSpec(t, vdmspec.Spec{Remotes: remotes})
+		assert.Error(t, sync())
+
+		_, err := os.Stat(remotes[0].LocalPath)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("--jobs must be positive", func(t *testing.T) {
+		chdirTemp(t)
+		resetSyncFlags(t)
+		SyncFlagValues.Jobs = 0
+
+		writeTestSpec(t, vdmspec.Spec{Remotes: makeRemotes("a")})
+		assert.Error(t, sync())
+	})
+}
@@ -0,0 +1,287 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/opensourcecorp/vdm/internal/message"
+	"github.com/opensourcecorp/vdm/internal/remotes"
+	"github.com/opensourcecorp/vdm/internal/vdmspec"
+	"github.com/spf13/cobra"
+)
+
+var updateCmd = &cobra.Command{
+	Use:   "update [remote...]",
+	Short: "Re-resolve remotes and rewrite the lockfile",
+	Long: `Re-resolve remotes from their sources, ignoring what's pinned in the lockfile,
+and rewrite the lockfile with the results. Remotes can be selected by their
+name, local path, or remote, and/or by --tag; if none are selected, all remotes
+are updated.
+
+With --to, the selected remotes' versions are first rewritten in the spec file
+itself (leaving its comments & formatting alone). Pass a version to set it
+directly, or 'latest-semver' to bump each git remote that's pinned to a semver
+tag to the newest semver tag on its remote, limited by --patch, --minor, or
+--major.`,
+	RunE: updateExecute,
+}
+
+type updateFlags struct {
+	Tags  []string
+	To    string
+	Patch bool
+	Minor bool
+	Major bool
+}
+
+// UpdateFlagValues contains an initalized [updateFlags] struct with populated
+// values.
+var UpdateFlagValues updateFlags
+
+// Flag name keys
+const (
+	toFlagKey    string = "to"
+	patchFlagKey string = "patch"
+	minorFlagKey string = "minor"
+	majorFlagKey string = "major"
+)
+
+// latestSemver is the special --to value that bumps remotes to their newest
+// semver tag.
+const latestSemver string = "latest-semver"
+
+func init() {
+	updateCmd.Flags().StringSliceVar(&UpdateFlagValues.Tags, tagFlagKey, nil, "Only update remotes with this tag (can be repeated)")
+	updateCmd.Flags().StringVar(&UpdateFlagValues.To, toFlagKey, "", fmt.Sprintf("Rewrite the selected remotes' versions in the spec file to this version, or '%s'", latestSemver))
+	updateCmd.Flags().BoolVar(&UpdateFlagValues.Patch, patchFlagKey, false, fmt.Sprintf("With --%s %s, only bump to newer patch versions", toFlagKey, latestSemver))
+	updateCmd.Flags().BoolVar(&UpdateFlagValues.Minor, minorFlagKey, false, fmt.Sprintf("With --%s %s, only bump to newer minor or patch versions", toFlagKey, latestSemver))
+	updateCmd.Flags().BoolVar(&UpdateFlagValues.Major, majorFlagKey, false, fmt.Sprintf("With --%s %s, bump to any newer version (the default)", toFlagKey, latestSemver))
+	updateCmd.MarkFlagsMutuallyExclusive(patchFlagKey, minorFlagKey, majorFlagKey)
+}
+
+func updateExecute(_ *cobra.Command, args []string) error {
+	MaybeSetDebug()
+	if err := update(args); err != nil {
+		return fmt.Errorf("executing update command: %w", err)
+	}
+	return nil
+}
+
+// bumpLimit is how far a remote's version can be bumped to a newer semver tag.
+type bumpLimit string
+
+// Bump limits
+const (
+	bumpPatch bumpLimit = "patch"
+	bumpMinor bumpLimit = "minor"
+	bumpMajor bumpLimit = "major"
+)
+
+// update syncs the spec, re-resolving the selected remotes. If --to was set,
+// their versions are rewritten in the spec file first.
+func update(selectors []string) error {
+	spec, err := vdmspec.GetSpecFromFile(RootFlagValues.SpecFilePath)
+	if err != nil {
+		return fmt.Errorf("getting specs from spec file: %w", err)
+	}
+
+	shouldUpdate, err := selectRemotes(spec, selectors, UpdateFlagValues.Tags)
+	if err != nil {
+		return err
+	}
+
+	hasLimit := UpdateFlagValues.Patch || UpdateFlagValues.Minor || UpdateFlagValues.Major
+	if hasLimit && UpdateFlagValues.To != latestSemver {
+		return fmt.Errorf("--%s, --%s, and --%s can only be used with --%s %s", patchFlagKey, minorFlagKey, majorFlagKey, toFlagKey, latestSemver)
+	}
+
+	if UpdateFlagValues.To != "" {
+		if err := rewriteVersions(spec, selectors, shouldUpdate); err != nil {
+			return err
+		}
+	}
+
+	return syncWithUpdates(shouldUpdate)
+}
+
+// rewriteVersions sets the version of every selected git remote in the spec
+// file, per the --to flag.
+func rewriteVersions(spec vdmspec.Spec, selectors []string, isSelected func(vdmspec.Remote) bool) error {
+	limit := bumpMajor
+	switch {
+	case UpdateFlagValues.To != latestSemver && len(selectors) == 0 && len(UpdateFlagValues.Tags) == 0:
+		return fmt.Errorf("setting every remote to version '%s' is probably a mistake, so select which remotes to set it for", UpdateFlagValues.To)
+	case UpdateFlagValues.Patch:
+		limit = bumpPatch
+	case UpdateFlagValues.Minor:
+		limit = bumpMinor
+	}
+
+	specFile, err := vdmspec.ReadSpecFile(RootFlagValues.SpecFilePath)
+	if err != nil {
+		return err
+	}
+
+	var changed int
+	for i, remote := range spec.Remotes {
+		if !isSelected(remote) {
+			continue
+		}
+		if remote.Type != vdmspec.GitType && remote.Type != "" {
+			message.Debugf("%s: not a git remote, so it has no version to rewrite", remote.OpMsg())
+			continue
+		}
+
+		version := UpdateFlagValues.To
+		if version == latestSemver {
+			tags, err := remotes.ListGitTags(remote)
+			if err != nil {
+				return fmt.Errorf("listing tags for %s: %w", remote.OpMsg(), err)
+			}
+			var isSemverTag bool
+			version, isSemverTag = newestSemverTag(remote.Version, tags, limit)
+			if !isSemverTag {
+				message.Infof("%s: not pinned to a semver tag, so leaving its version as-is", remote.OpMsg())
+				continue
+			}
+		}
+
+		if version == remote.Version {
+			message.Infof("%s: already at version '%s'", remote.OpMsg(), version)
+			continue
+		}
+
+		message.Infof("%s: Setting version to '%s' in spec file", remote.OpMsg(), version)
+		if err := specFile.SetVersion(i, version); err != nil {
+			return fmt.Errorf("setting version for %s: %w", remote.OpMsg(), err)
+		}
+		changed++
+	}
+
+	if changed == 0 {
+		return nil
+	}
+
+	newSpec, err := specFile.Spec()
+	if err != nil {
+		return fmt.Errorf("reading rewritten spec file: %w", err)
+	}
+	if err := newSpec.Validate(); err != nil {
+		return fmt.Errorf("rewritten spec file would be malformed, so not writing it: %w", err)
+	}
+
+	return specFile.Write()
+}
+
+// newestSemverTag returns the newest of the tags that's a semver version, and is
+// no more than the bump limit ahead of the current version. Pre-releases are
+// only considered if the current version is one. If nothing is newer, the
+// current version is returned. It also reports whether the current version is a
+// semver tag at all, since only those can be compared.
+func newestSemverTag(current string, tags []string, limit bumpLimit) (string, bool) {
+	isTag := false
+	for _, tag := range tags {
+		if tag == current {
+			isTag = true
+			break
+		}
+	}
+	currentVersion, err := semver.NewVersion(current)
+	if !isTag || err != nil {
+		return current, false
+	}
+
+	newest, newestTag := currentVersion, current
+	for _, tag := range tags {
+		version, err := semver.NewVersion(tag)
+		if err != nil {
+			continue
+		}
+		if version.Prerelease() != "" && currentVersion.Prerelease() == "" {
+			continue
+		}
+		if limit != bumpMajor && version.Major() != currentVersion.Major() {
+			continue
+		}
+		if limit == bumpPatch && version.Minor() != currentVersion.Minor() {
+			continue
+		}
+		if version.GreaterThan(newest) {
+			newest, newestTag = version, tag
+		}
+	}
+
+	return newestTag, true
+}
+
+// selectRemotes returns a function that reports whether a remote was selected
+// by any of the provided selectors, each of which can be a remote's name (see
+// [vdmspec.Remote.DisplayName]), local path, or remote. No selectors selects
+// every remote. If any tags are provided, only remotes with at least one of them
+// are selected. Selectors and tags that don't match anything in the spec are an
+// error, since they're probably a typo. So are names that more than one remote
+// has, since names derived from local paths don't have to be unique.
+func selectRemotes(spec vdmspec.Spec, selectors []string, tags []string) (func(vdmspec.Remote) bool, error) {
+	matches := func(remote vdmspec.Remote, selector string) bool {
+		return remote.Remote == selector ||
+			remote.DisplayName() == selector ||
+			filepath.Clean(remote.LocalPath) == filepath.Clean(selector)
+	}
+
+	for _, selector := range selectors {
+		found := false
+		var namedPaths []string
+		for _, remote := range spec.Remotes {
+			if matches(remote, selector) {
+				found = true
+			}
+			if remote.DisplayName() == selector {
+				namedPaths = append(namedPaths, remote.LocalPath)
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no remote in the spec file has a name, local path, or remote matching '%s'", selector)
+		}
+		if len(namedPaths) > 1 {
+			return nil, fmt.Errorf("more than one remote is named '%s' (at local paths '%s'), so select one by its local path instead, or set 'name' on them to tell them apart", selector, strings.Join(namedPaths, "', '"))
+		}
+	}
+	for _, tag := range tags {
+		found := false
+		for _, remote := range spec.Remotes {
+			if remote.HasTag(tag) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no remote in the spec file has tag '%s'", tag)
+		}
+	}
+
+	return func(remote vdmspec.Remote) bool {
+		if len(tags) > 0 {
+			hasTag := false
+			for _, tag := range tags {
+				if remote.HasTag(tag) {
+					hasTag = true
+					break
+				}
+			}
+			if !hasTag {
+				return false
+			}
+		}
+		if len(selectors) == 0 {
+			return true
+		}
+		for _, selector := range selectors {
+			if matches(remote, selector) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opensourcecorp/vdm/internal/vdmspec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// resetUpdateFlags puts the update flags back to their defaults, both now and
+// when the test finishes.
+func resetUpdateFlags(t *testing.T) {
+	t.Helper()
+
+	UpdateFlagValues = updateFlags{}
+	t.Cleanup(func() { UpdateFlagValues = updateFlags{} })
+}
+
+func TestNewestSemverTag(t *testing.T) {
+	tags := []string{"v1.0.0", "v1.0.1", "v1.1.0", "v1.2.0-rc.1", "v2.0.0", "main-snapshot"}
+
+	for _, tc := range []struct {
+		current    string
+		limit      bumpLimit
+		want       string
+		wantSemver bool
+	}{
+		{current: "v1.0.0", limit: bumpPatch, want: "v1.0.1", wantSemver: true},
+		{current: "v1.0.0", limit: bumpMinor, want: "v1.1.0", wantSemver: true},
+		{current: "v1.0.0", limit: bumpMajor, want: "v2.0.0", wantSemver: true},
+		{current: "v2.0.0", limit: bumpMajor, want: "v2.0.0", wantSemver: true},
+		{current: "v1.2.0-rc.1", limit: bumpPatch, want: "v1.2.0-rc.1", wantSemver: true},
+		{current: "v1.2.0-rc.1", limit: bumpMinor, want: "v1.2.0-rc.1", wantSemver: true},
+		{current: "main-snapshot", limit: bumpMajor, want: "main-snapshot"},
+		{current: "1234567", limit: bumpMajor, want: "1234567"},
+	} {
+		got, isSemver := newestSemverTag(tc.current, tags, tc.limit)
+		assert.Equal(t, tc.want, got, "%s, %s", tc.current, tc.limit)
+		assert.Equal(t, tc.wantSemver, isSemver, "%s, %s", tc.current, tc.limit)
+	}
+}
+
+func TestUpdateTo(t *testing.T) {
+	remoteURL, commit := newTestGitServer(t)
+	commit("v1.0.0", "v1.0.0")
+	commit("v1.0.1", "v1.0.1")
+	commit("v1.1.0", "v1.1.0")
+	commit("v2.0.0", "v2.0.0")
+	chdirTemp(t)
+	resetSyncFlags(t)
+	resetUpdateFlags(t)
+
+	specTemplate := `# vendored deps
+remotes:
+  - remote:     %q
+    local_path: "./deps/common"
+    version:    %q # keep an eye on this one
+
+  - remote:     %q
+    local_path: "./deps/tracking"
+    version:    "main"
+`
+	specFor := func(version string) string {
+		return fmt.Sprintf(specTemplate, remoteURL, version, remoteURL)
+	}
+	require.NoError(t, os.WriteFile("vdm.yaml", []byte(specFor("v1.0.0")), 0644))
+	RootFlagValues.SpecFilePath = "./vdm.yaml"
+	require.NoError(t, sync())
+
+	requireVersion := func(t *testing.T, version string) {
+		t.Helper()
+
+		specContents, err := os.ReadFile(RootFlagValues.SpecFilePath)
+		require.NoError(t, err)
+		assert.Equal(t, specFor(version), string(specContents))
+
+		got, err := os.ReadFile(filepath.Join("deps", "common", "contents.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, version, string(got))
+
+		vdmMeta, err := vdmspec.Remote{LocalPath: "./deps/common"}.GetVDMMeta()
+		require.NoError(t, err)
+		assert.Equal(t, version, vdmMeta.Version)
+	}
+
+	for _, tc := range []struct {
+		flags updateFlags
+		want  string
+	}{
+		{flags: updateFlags{To: latestSemver, Patch: true}, want: "v1.0.1"},
+		{flags: updateFlags{To: latestSemver, Minor: true}, want: "v1.1.0"},
+		{flags: updateFlags{To: latestSemver}, want: "v2.0.0"},
+	} {
+		t.Run(fmt.Sprintf("bumps to %s with %+v", tc.want, tc.flags), func(t *testing.T) {
+			UpdateFlagValues = tc.flags
+			require.NoError(t, update(nil))
+			requireVersion(t, tc.want)
+		})
+	}
+
+	t.Run("sets a specific version for selected remotes", func(t *testing.T) {
+		UpdateFlagValues = updateFlags{To: "v1.0.0"}
+		require.NoError(t, update([]string{"common"}))
+		requireVersion(t, "v1.0.0")
+	})
+
+	t.Run("fails to set a specific version for every remote", func(t *testing.T) {
+		UpdateFlagValues = updateFlags{To: "v1.0.0"}
+		assert.Error(t, update(nil))
+	})
+
+	t.Run("fails with a bump limit but no --to latest-semver", func(t *testing.T) {
+		UpdateFlagValues = updateFlags{Minor: true}
+		assert.Error(t, update(nil))
+		UpdateFlagValues = updateFlags{To: "v1.1.0", Minor: true}
+		assert.Error(t, update([]string{"common"}))
+	})
+
+	t.Run("doesn't write a malformed spec", func(t *testing.T) {
+		UpdateFlagValues = updateFlags{To: "^not-a-version"}
+		assert.Error(t, update([]string{"common"}))
+		requireVersion(t, "v1.0.0")
+	})
+}
+
+func TestSelectRemotes(t *testing.T) {
+	common := vdmspec.Remote{Name: "common", Remote: "https://example.com/go-common", Version: "v1.0.0", LocalPath: "./deps/go-common", Tags: []string{"go"}}
+	proto := vdmspec.Remote{Type: vdmspec.FileType, Remote: "https://example.com/http.proto", LocalPath: "./deps/http.proto", Tags: []string{"proto", "api"}}
+	other := vdmspec.Remote{Remote: "https://example.com/other", Version: "main", LocalPath: "./deps/other", Tags: []string{"go"}}
+	spec := vdmspec.Spec{Remotes: []vdmspec.Remote{common, proto, other}}
+
+	selected := func(t *testing.T, selectors []string, tags []string) []string {
+		t.Helper()
+
+		isSelected, err := selectRemotes(spec, selectors, tags)
+		require.NoError(t, err)
+		var names []string
+		for _, remote := range spec.Remotes {
+			if isSelected(remote) {
+				names = append(names, remote.DisplayName())
+			}
+		}
+		return names
+	}
+
+	assert.Equal(t, []string{"common", "http.proto", "other"}, selected(t, nil, nil))
+	assert.Equal(t, []string{"common", "http.proto"}, selected(t, []string{"common", "deps/http.proto"}, nil))
+	assert.Equal(t, []string{"other"}, selected(t, []string{"https://example.com/other"}, nil))
+	assert.Equal(t, []string{"common", "other"}, selected(t, nil, []string{"go"}))
+	assert.Equal(t, []string{"common", "http.proto", "other"}, selected(t, nil, []string{"go", "api"}))
+	assert.Equal(t, []string{"other"}, selected(t, []string{"other", "http.proto"}, []string{"go"}))
+
+	for _, tc := range []struct {
+		selectors []string
+		tags      []string
+	}{
+		{selectors: []string{"go-common"}},
+		{tags: []string{"rust"}},
+	} {
+		_, err := selectRemotes(spec, tc.selectors, tc.tags)
+		assert.Error(t, err, "%v %v", tc.selectors, tc.tags)
+	}
+
+	t.Run("names shared by more than one remote are ambiguous", func(t *testing.T) {
+		first := vdmspec.Remote{Remote: "https://example.com/a", Version: "main", LocalPath: "./deps/a/proto"}
+		second := vdmspec.Remote{Remote: "https://example.com/b", Version: "main", LocalPath: "./deps/b/proto"}
+		spec := vdmspec.Spec{Remotes: []vdmspec.Remote{first, second}}
+		require.NoError(t, spec.Validate())
+
+		_, err := selectRemotes(spec, []string{"proto"}, nil)
+		assert.ErrorContains(t, err, "more than one remote is named 'proto'")
+
+		isSelected, err := selectRemotes(spec, []string{"./deps/b/proto"}, nil)
+		require.NoError(t, err)
+		assert.False(t, isSelected(first))
+		assert.True(t, isSelected(second))
+	})
+}
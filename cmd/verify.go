@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/opensourcecorp/vdm/internal/message"
+	"github.com/opensourcecorp/vdm/internal/vdmspec"
+	"github.com/spf13/cobra"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify [remote...]",
+	Short: "Check that synced remotes haven't been edited locally",
+	Long: `Check that the content at each remote's local path still matches what vdm
+placed there when it was synced, and report any files that were modified, added,
+or deleted since. Remotes can be selected by their name, local path, or remote,
+and/or by --tag; if none are selected, all remotes are verified. Exits non-zero
+if anything has drifted.`,
+	RunE: verifyExecute,
+}
+
+type verifyFlags struct {
+	Output string
+	Tags   []string
+}
+
+// VerifyFlagValues contains an initalized [verifyFlags] struct with populated
+// values.
+var VerifyFlagValues verifyFlags
+
+func init() {
+	verifyCmd.Flags().StringVarP(&VerifyFlagValues.Output, outputFlagKey, "o", outputFormatText, "Output format, one of 'text' or 'json'")
+	verifyCmd.Flags().StringSliceVar(&VerifyFlagValues.Tags, tagFlagKey, nil, "Only verify remotes with this tag (can be repeated)")
+}
+
+func verifyExecute(_ *cobra.Command, args []string) error {
+	MaybeSetDebug()
+	if err := verify(args); err != nil {
+		return fmt.Errorf("executing verify command: %w", err)
+	}
+	return nil
+}
+
+// verifyStatus is the outcome of verifying a single remote.
+type verifyStatus string
+
+// Verify statuses
+const (
+	// verifyStatusOK means the remote's content is exactly what was synced.
+	verifyStatusOK verifyStatus = "ok"
+	// verifyStatusDrifted means the remote's content has been edited since it
+	// was synced.
+	verifyStatusDrifted verifyStatus = "drifted"
+	// verifyStatusNotSynced means the remote hasn't been synced as it's
+	// currently specified, so there's nothing to verify it against.
+	verifyStatusNotSynced verifyStatus = "not-synced"
+)
+
+// remoteVerification is the result of verifying a single remote.
+type remoteVerification struct {
+	Remote vdmspec.Remote `json:"remote"`
+	Status verifyStatus   `json:"status"`
+	vdmspec.ManifestDiff
+}
+
+// verifyReport is the result of verifying every selected remote.
+type verifyReport struct {
+	Remotes []remoteVerification `json:"remotes"`
+}
+
+// verify checks the selected remotes' content on disk against the manifests in
+// their metafiles, and returns an error if any of them don't match.
+func verify(selectors []string) error {
+	if err := checkOutputFormat(VerifyFlagValues.Output); err != nil {
+		return err
+	}
+
+	spec, err := vdmspec.GetSpecFromFile(RootFlagValues.SpecFilePath)
+	if err != nil {
+		return fmt.Errorf("getting specs from spec file: %w", err)
+	}
+
+	isSelected, err := selectRemotes(spec, selectors, VerifyFlagValues.Tags)
+	if err != nil {
+		return err
+	}
+
+	var report verifyReport
+	for _, remote := range spec.Remotes {
+		if !isSelected(remote) {
+			continue
+		}
+		result, err := verifyRemote(remote)
+		if err != nil {
+			return fmt.Errorf("verifying %s: %w", remote.OpMsg(), err)
+		}
+		report.Remotes = append(report.Remotes, result)
+	}
+
+	if err := report.print(VerifyFlagValues.Output); err != nil {
+		return err
+	}
+
+	var failed int
+	for _, result := range report.Remotes {
+		if result.Status != verifyStatusOK {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d remote(s) don't match what was synced", failed)
+	}
+
+	return nil
+}
+
+// verifyRemote compares the remote's content on disk against the manifest in
+// its metafile.
+func verifyRemote(remote vdmspec.Remote) (remoteVerification, error) {
+	result := remoteVerification{Remote: remote}
+
+	vdmMeta, err := remote.GetVDMMeta()
+	if err != nil {
+		return remoteVerification{}, fmt.Errorf("getting vdm metadata file: %w", err)
+	}
+	if vdmMeta.IsZero() || !vdmMeta.Remote.Equal(remote) {
+		result.Status = verifyStatusNotSynced
+		return result, nil
+	}
+
+	current, err := remote.BuildManifest()
+	if err != nil {
+		return remoteVerification{}, fmt.Errorf("building manifest: %w", err)
+	}
+
+	result.ManifestDiff = vdmMeta.Manifest.Diff(current)
+	result.Status = verifyStatusOK
+	if !result.ManifestDiff.IsEmpty() {
+		result.Status = verifyStatusDrifted
+	}
+
+	return result, nil
+}
+
+// print writes out the report in the provided output format.
+func (r verifyReport) print(format string) error {
+	switch format {
+	case outputFormatJSON:
+		reportJSON, err := json.MarshalIndent(r, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshalling verify report to JSON: %w", err)
+		}
+		message.Infof("%s", string(reportJSON))
+	default:
+		if len(r.Remotes) == 0 {
+			message.Infof("No remotes in spec file; nothing to verify")
+			return nil
+		}
+		for _, result := range r.Remotes {
+			switch result.Status {
+			case verifyStatusOK:
+				message.Infof("%s: OK", result.Remote.OpMsg())
+			case verifyStatusNotSynced:
+				message.Errorf("%s: not synced as currently specified, so can't be verified (run 'vdm sync')", result.Remote.OpMsg())
+			case verifyStatusDrifted:
+				message.Errorf("%s: local content has drifted from what was synced", result.Remote.OpMsg())
+				for _, file := range result.Modified {
+					message.Infof("  modified: %s", file)
+				}
+				for _, file := range result.Added {
+					message.Infof("  added:    %s", file)
+				}
+				for _, file := range result.Deleted {
+					message.Infof("  deleted:  %s", file)
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opensourcecorp/vdm/internal/vdmspec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	remoteURL, _ := newTestGitServer(t)
+	server := newTestFileServer(t)
+	chdirTemp(t)
+	resetSyncFlags(t)
+
+	gitRemote := vdmspec.Remote{Remote: remoteURL, Version: "main", LocalPath: "./deps/git"}
+	fileRemote := vdmspec.Remote{Type: vdmspec.FileType, Remote: server.URL + "/some.file", LocalPath: "./deps/some.file"}
+	writeTestSpec(t, vdmspec.Spec{Remotes: []vdmspec.Remote{gitRemote, fileRemote}})
+
+	t.Run("remotes that aren't synced yet fail", func(t *testing.T) {
+		result, err := verifyRemote(gitRemote)
+		require.NoError(t, err)
+		assert.Equal(t, verifyStatusNotSynced, result.Status)
+		assert.Error(t, verify(nil))
+	})
+
+	require.NoError(t, sync())
+
+	t.Run("passes right after a sync", func(t *testing.T) {
+		assert.NoError(t, verify(nil))
+	})
+
+	t.Run("reports drift", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(gitRemote.LocalPath, "contents.txt"), []byte("edited"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(gitRemote.LocalPath, "new.txt"), []byte("new"), 0644))
+
+		result, err := verifyRemote(gitRemote)
+		require.NoError(t, err)
+		assert.Equal(t, verifyStatusDrifted, result.Status)
+		assert.Equal(t, []string{"contents.txt"}, result.Modified)
+		assert.Equal(t, []string{"new.txt"}, result.Added)
+
+		assert.Error(t, verify(nil))
+		assert.NoError(t, verify([]string{fileRemote.LocalPath}))
+	})
+
+	t.Run("reports deleted files", func(t *testing.T) {
+		require.NoError(t, os.Remove(fileRemote.LocalPath))
+
+		result, err := verifyRemote(fileRemote)
+		require.NoError(t, err)
+		assert.Equal(t, verifyStatusDrifted, result.Status)
+		assert.Equal(t, []string{"some.file"}, result.Deleted)
+	})
+
+	t.Run("report marshals to JSON", func(t *testing.T) {
+		result, err := verifyRemote(gitRemote)
+		require.NoError(t, err)
+
+		reportJSON, err := json.Marshal(verifyReport{Remotes: []remoteVerification{result}})
+		require.NoError(t, err)
+
+		var got map[string][]map[string]any
+		require.NoError(t, json.Unmarshal(reportJSON, &got))
+		assert.Equal(t, "drifted", got["remotes"][0]["status"])
+		assert.Equal(t, []any{"contents.txt"}, got["remotes"][0]["modified"])
+	})
+
+	t.Run("fails on unrecognized output format", func(t *testing.T) {
+		VerifyFlagValues.Output = "xml"
+		defer func() { VerifyFlagValues.Output = outputFormatText }()
+		assert.Error(t, verify(nil))
+	})
+}
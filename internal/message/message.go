@@ -4,38 +4,124 @@ package message
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"strings"
+	"sync"
 )
 
+var (
+	// output is where all messages are printed to.
+	output io.Writer = os.Stdout
+	// outputMu keeps concurrent callers from interleaving their output.
+	outputMu sync.Mutex
+)
+
+func printLines(lines ...string) {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	for _, line := range lines {
+		fmt.Fprint(output, line)
+	}
+}
+
+func debugLine(format string, args ...any) string {
+	if os.Getenv("DEBUG") == "" {
+		return ""
+	}
+	return fmt.Sprintf("DEBUG: "+format+"\n", args...)
+}
+
 // Debugf prints out debug-level information messages with a formatting
 // directive.
 func Debugf(format string, args ...any) {
-	if os.Getenv("DEBUG") != "" {
-		fmt.Printf("DEBUG: "+format+"\n", args...)
+	if line := debugLine(format, args...); line != "" {
+		printLines(line)
 	}
 }
 
 // Infof prints out debug-level information messages with a formatting
 // directive.
 func Infof(format string, args ...any) {
-	fmt.Printf(format+"\n", args...)
+	printLines(fmt.Sprintf(format+"\n", args...))
 }
 
 // Warnf prints out debug-level information messages with a formatting
 // directive.
 func Warnf(format string, args ...any) {
-	fmt.Printf("WARNING: "+format+"\n", args...)
+	printLines(fmt.Sprintf("WARNING: "+format+"\n", args...))
 }
 
 // Errorf prints out debug-level information messages with a formatting
 // directive.
 func Errorf(format string, args ...any) {
-	fmt.Printf("ERROR: "+format+"\n", args...)
+	printLines(fmt.Sprintf("ERROR: "+format+"\n", args...))
 }
 
 // Fatalf prints out debug-level information messages with a formatting
 // directive, and then exits with code 1.
 func Fatalf(format string, args ...any) {
-	fmt.Printf("ERROR: "+format+"\n", args...)
+	printLines(fmt.Sprintf("ERROR: "+format+"\n", args...))
 	os.Exit(1)
 }
+
+// Group collects the messages for a single unit of work, so that work running
+// concurrently with other work doesn't interleave its output with theirs.
+// Nothing is printed until [Group.Flush] is called. A nil *Group is valid, and
+// prints each message immediately instead.
+type Group struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+// NewGroup returns an empty [Group].
+func NewGroup() *Group {
+	return &Group{}
+}
+
+func (g *Group) add(line string) {
+	if line == "" {
+		return
+	}
+	if g == nil {
+		printLines(line)
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.lines = append(g.lines, line)
+}
+
+// Debugf is [Debugf], but collected into the group.
+func (g *Group) Debugf(format string, args ...any) {
+	g.add(debugLine(format, args...))
+}
+
+// Infof is [Infof], but collected into the group.
+func (g *Group) Infof(format string, args ...any) {
+	g.add(fmt.Sprintf(format+"\n", args...))
+}
+
+// Warnf is [Warnf], but collected into the group.
+func (g *Group) Warnf(format string, args ...any) {
+	g.add(fmt.Sprintf("WARNING: "+format+"\n", args...))
+}
+
+// Errorf is [Errorf], but collected into the group.
+func (g *Group) Errorf(format string, args ...any) {
+	g.add(fmt.Sprintf("ERROR: "+format+"\n", args...))
+}
+
+// Flush prints every message collected so far in one go, and empties the
+// group.
+func (g *Group) Flush() {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	lines := g.lines
+	g.lines = nil
+	g.mu.Unlock()
+
+	printLines(strings.Join(lines, ""))
+}
@@ -0,0 +1,67 @@
+package message
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// captureOutput redirects all messages into the returned buffer for the
+// duration of the test.
+func captureOutput(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	buf := new(bytes.Buffer)
+	oldOutput := output
+	output = buf
+	t.Cleanup(func() { output = oldOutput })
+
+	return buf
+}
+
+func TestGroup(t *testing.T) {
+	t.Run("nothing is printed until flushed", func(t *testing.T) {
+		buf := captureOutput(t)
+
+		g := NewGroup()
+		g.Infof("first %d", 1)
+		g.Warnf("second")
+		assert.Empty(t, buf.String())
+
+		g.Flush()
+		assert.Equal(t, "first 1\nWARNING: second\n", buf.String())
+	})
+
+	t.Run("concurrent groups don't interleave", func(t *testing.T) {
+		buf := captureOutput(t)
+
+		var wg sync.WaitGroup
+		for _, name := range []string{"a", "b", "c"} {
+			wg.Add(1)
+			go func(name string) {
+				defer wg.Done()
+				g := NewGroup()
+				for i := 0; i < 3; i++ {
+					g.Infof("%s", name)
+				}
+				g.Flush()
+			}(name)
+		}
+		wg.Wait()
+
+		got := buf.String()
+		for _, name := range []string{"a", "b", "c"} {
+			assert.Contains(t, got, name+"\n"+name+"\n"+name+"\n")
+		}
+	})
+
+	t.Run("nil group prints immediately", func(t *testing.T) {
+		buf := captureOutput(t)
+
+		var g *Group
+		g.Errorf("oops")
+		assert.Equal(t, "ERROR: oops\n", buf.String())
+	})
+}
@@ -0,0 +1,457 @@
+package remotes
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/opensourcecorp/vdm/internal/message"
+	"github.com/opensourcecorp/vdm/internal/vdmspec"
+	"github.com/ulikunitz/xz"
+)
+
+// archiveExtensions maps file extensions to the archive formats they indicate.
+// Longer extensions are listed first, so that e.g. '.tar.gz' isn't mistaken for
+// '.tar'.
+var archiveExtensions = []struct {
+	extension string
+	format    string
+}{
+	{extension: ".tar.gz", format: vdmspec.ArchiveTarGz},
+	{extension: ".tgz", format: vdmspec.ArchiveTarGz},
+	{extension: ".tar.xz", format: vdmspec.ArchiveTarXz},
+	{extension: ".txz", format: vdmspec.ArchiveTarXz},
+	{extension: ".tar.bz2", format: vdmspec.ArchiveTarBz2},
+	{extension: ".tbz2", format: vdmspec.ArchiveTarBz2},
+	{extension: ".tar.zst", format: vdmspec.ArchiveTarZst},
+	{extension: ".tzst", format: vdmspec.ArchiveTarZst},
+	{extension: ".tar", format: vdmspec.ArchiveTar},
+	{extension: ".zip", format: vdmspec.ArchiveZip},
+}
+
+// archiveContentTypes maps the content types that servers send for archives to
+// the archive formats they indicate. Content types that don't say whether
+// there's a tarball inside (like 'application/gzip') are left out.
+var archiveContentTypes = map[string]string{
+	"application/x-tar":                 vdmspec.ArchiveTar,
+	"application/x-gtar":                vdmspec.ArchiveTarGz,
+	"application/x-compressed-tar":      vdmspec.ArchiveTarGz,
+	"application/x-xz-compressed-tar":   vdmspec.ArchiveTarXz,
+	"application/x-bzip-compressed-tar": vdmspec.ArchiveTarBz2,
+	"application/x-zstd-compressed-tar": vdmspec.ArchiveTarZst,
+	"application/zip":                   vdmspec.ArchiveZip,
+	"application/x-zip-compressed":      vdmspec.ArchiveZip,
+}
+
+// detectArchiveFormat works out which archive format a file remote should be
+// unpacked from, if any, preferring what's set in the spec, then the remote's
+// file extension, then the content type the server sent. An empty string means
+// the file isn't an archive.
+func detectArchiveFormat(remote vdmspec.Remote, contentType string) string {
+	switch remote.Archive {
+	case vdmspec.ArchiveNone:
+		return ""
+	case "":
+	default:
+		return remote.Archive
+	}
+
+	remotePath := remote.Remote
+	if remoteURL, err := url.Parse(remote.Remote); err == nil {
+		remotePath = remoteURL.Path
+	}
+	remotePath = strings.ToLower(remotePath)
+	for _, archiveExtension := range archiveExtensions {
+		if strings.HasSuffix(remotePath, archiveExtension.extension) {
+			return archiveExtension.format
+		}
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	return archiveContentTypes[mediaType]
+}
+
+// extractArchive unpacks the archive at archivePath into destDir, removing the
+// number of leading path components set by the remote's strip_components from
+// each entry. Entries that would land outside of destDir, whether by their own
+// path or by way of a symlink, are rejected.
+func extractArchive(remote vdmspec.Remote, archivePath string, destDir string, format string, out *message.Group) error {
+	out.Infof("%s: Unpacking %s archive...", remote.OpMsg(), format)
+	if err := os.MkdirAll(destDir, os.ModePerm); err != nil {
+		return fmt.Errorf("making directory to unpack archive into: %w", err)
+	}
+
+	if format == vdmspec.ArchiveZip {
+		return extractZip(archivePath, destDir, remote.StripComponents, out)
+	}
+	return extractTar(archivePath, destDir, format, remote.StripComponents, out)
+}
+
+func extractTar(archivePath string, destDir string, format string, stripComponents int, out *message.Group) (err error) {
+	archiveFile, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("opening archive: %w", err)
+	}
+	defer func() {
+		if closeErr := archiveFile.Close(); closeErr != nil {
+			err = errors.Join(err, fmt.Errorf("closing archive: %w", closeErr))
+		}
+	}()
+
+	var reader io.Reader
+	switch format {
+	case vdmspec.ArchiveTar:
+		reader = archiveFile
+	case vdmspec.ArchiveTarGz:
+		var gzipReader *gzip.Reader
+		gzipReader, err = gzip.NewReader(archiveFile)
+		if err != nil {
+			return fmt.Errorf("reading gzip stream: %w", err)
+		}
+		defer func() {
+			if closeErr := gzipReader.Close(); closeErr != nil {
+				err = errors.Join(err, fmt.Errorf("closing gzip stream: %w", closeErr))
+			}
+		}()
+		reader = gzipReader
+	case vdmspec.ArchiveTarXz:
+		reader, err = xz.NewReader(archiveFile)
+		if err != nil {
+			return fmt.Errorf("reading xz stream: %w", err)
+		}
+	case vdmspec.ArchiveTarBz2:
+		reader = bzip2.NewReader(archiveFile)
+	case vdmspec.ArchiveTarZst:
+		// There's no zstd decoder in the standard library, so this leans on the
+		// zstd CLI the same way that git remotes lean on git
+		var wait func() error
+		reader, wait, err = zstdDecompress(archiveFile, out)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if waitErr := wait(); waitErr != nil {
+				err = errors.Join(err, waitErr)
+			}
+		}()
+	default:
+		return fmt.Errorf("unrecognized archive format '%s'", format)
+	}
+
+	tarReader := tar.NewReader(reader)
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return fmt.Errorf("reading archive: %w", err)
+		}
+
+		target, err := archiveEntryPath(destDir, header.Name, stripComponents)
+		if err != nil {
+			return err
+		}
+		if target == "" {
+			out.Debugf("skipping archive entry '%s', which is stripped away entirely", header.Name)
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = makeArchiveDir(destDir, target)
+		case tar.TypeReg:
+			err = writeArchiveFile(destDir, target, header.FileInfo().Mode(), tarReader)
+		case tar.TypeSymlink:
+			err = makeArchiveSymlink(destDir, target, header.Linkname)
+		case tar.TypeLink:
+			var linkTarget string
+			linkTarget, err = archiveEntryPath(destDir, header.Linkname, stripComponents)
+			if err == nil && linkTarget == "" {
+				err = fmt.Errorf("archive entry '%s' links to '%s', which is stripped away entirely", header.Name, header.Linkname)
+			}
+			if err == nil {
+				err = makeArchiveHardlink(destDir, target, linkTarget)
+			}
+		default:
+			out.Debugf("skipping archive entry '%s' of unsupported type '%c'", header.Name, header.Typeflag)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("unpacking archive entry '%s': %w", header.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func extractZip(archivePath string, destDir string, stripComponents int, out *message.Group) (err error) {
+	zipReader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("opening zip archive: %w", err)
+	}
+	defer func() {
+		if closeErr := zipReader.Close(); closeErr != nil {
+			err = errors.Join(err, fmt.Errorf("closing zip archive: %w", closeErr))
+		}
+	}()
+
+	for _, entry := range zipReader.File {
+		target, err := archiveEntryPath(destDir, entry.Name, stripComponents)
+		if err != nil {
+			return err
+		}
+		if target == "" {
+			out.Debugf("skipping archive entry '%s', which is stripped away entirely", entry.Name)
+			continue
+		}
+
+		if err := extractZipEntry(destDir, target, entry); err != nil {
+			return fmt.Errorf("unpacking archive entry '%s': %w", entry.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func extractZipEntry(destDir string, target string, entry *zip.File) (err error) {
+	mode := entry.Mode()
+	if mode.IsDir() {
+		return makeArchiveDir(destDir, target)
+	}
+
+	contents, err := entry.Open()
+	if err != nil {
+		return fmt.Errorf("opening entry: %w", err)
+	}
+	defer func() {
+		if closeErr := contents.Close(); closeErr != nil {
+			err = errors.Join(err, fmt.Errorf("closing entry: %w", closeErr))
+		}
+	}()
+
+	if mode&os.ModeSymlink != 0 {
+		linkname, err := io.ReadAll(contents)
+		if err != nil {
+			return fmt.Errorf("reading symlink target: %w", err)
+		}
+		return makeArchiveSymlink(destDir, target, string(linkname))
+	}
+
+	return writeArchiveFile(destDir, target, mode, contents)
+}
+
+// archiveEntryPath returns where an archive entry should be unpacked to inside
+// destDir, after stripping the provided number of leading path components from
+// it. An empty path means the entry is stripped away entirely. Entries with
+// absolute paths, or that would otherwise land outside of destDir, are an
+// error.
+func archiveEntryPath(destDir string, name string, stripComponents int) (string, error) {
+	// Archive entries always use forward slashes, but some tools still write
+	// backslashes on Windows
+	name = strings.ReplaceAll(name, `\`, "/")
+	if strings.HasPrefix(name, "/") || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("archive entry '%s' has an absolute path, which isn't allowed", name)
+	}
+
+	var components []string
+	for _, component := range strings.Split(name, "/") {
+		if component != "" && component != "." {
+			components = append(components, component)
+		}
+	}
+	if len(components) <= stripComponents {
+		return "", nil
+	}
+
+	relPath := filepath.Clean(filepath.Join(components[stripComponents:]...))
+	if relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("archive entry '%s' would be unpacked outside of the local path, which isn't allowed", name)
+	}
+
+	return filepath.Join(destDir, relPath), nil
+}
+
+// checkArchiveParents returns an error if any of the directories between
+// destDir and the target path are symlinks, since writing through them could
+// land outside of destDir.
+func checkArchiveParents(destDir string, target string) error {
+	relPath, err := filepath.Rel(destDir, filepath.Dir(target))
+	if err != nil {
+		return fmt.Errorf("determining path of '%s' relative to '%s': %w", target, destDir, err)
+	}
+	if relPath == "." {
+		return nil
+	}
+
+	current := destDir
+	for _, component := range strings.Split(relPath, string(filepath.Separator)) {
+		current = filepath.Join(current, component)
+		info, err := os.Lstat(current)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("checking '%s': %w", current, err)
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("'%s' is a symlink, so nothing can be unpacked through it", current)
+		}
+	}
+
+	return nil
+}
+
+func makeArchiveDir(destDir string, target string) error {
+	if err := checkArchiveParents(destDir, target); err != nil {
+		return err
+	}
+	return os.MkdirAll(target, 0755)
+}
+
+func writeArchiveFile(destDir string, target string, mode os.FileMode, contents io.Reader) (err error) {
+	if err := checkArchiveParents(destDir, target); err != nil {
+		return err
+	}
+	// Opening the target would follow it if it were already a symlink
+	if info, err := os.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("'%s' is a symlink, so nothing can be unpacked through it", target)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("making parent directories: %w", err)
+	}
+
+	// Only the permission bits are kept, so nothing unpacked is setuid etc.
+	outFile, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode.Perm()|0600)
+	if err != nil {
+		return fmt.Errorf("creating file: %w", err)
+	}
+	defer func() {
+		if closeErr := outFile.Close(); closeErr != nil {
+			err = errors.Join(err, fmt.Errorf("closing file: %w", closeErr))
+		}
+	}()
+
+	if _, err := io.Copy(outFile, contents); err != nil {
+		return fmt.Errorf("writing file: %w", err)
+	}
+
+	return nil
+}
+
+// makeArchiveSymlink creates a symlink from an archive, as long as it's
+// relative, points somewhere inside destDir, and doesn't get there by way of
+// any other symlinks (which would make where it really points differ from
+// where it looks like it points).
+func makeArchiveSymlink(destDir string, target string, linkname string) error {
+	if err := checkArchiveParents(destDir, target); err != nil {
+		return err
+	}
+
+	if filepath.IsAbs(linkname) || strings.HasPrefix(linkname, "/") {
+		return fmt.Errorf("symlink to '%s' has an absolute target, which isn't allowed", linkname)
+	}
+	resolved := filepath.Join(filepath.Dir(target), filepath.FromSlash(linkname))
+	relPath, err := filepath.Rel(destDir, resolved)
+	if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("symlink to '%s' points outside of the local path, which isn't allowed", linkname)
+	}
+
+	// '..' is only allowed at the start of the link, where it walks back up
+	// through the directories that were already checked not to be symlinks.
+	// Anywhere else, it could walk back out of a symlink to somewhere else.
+	components := strings.Split(linkname, "/")
+	current := filepath.Dir(target)
+	descended := false
+	for i, component := range components {
+		switch component {
+		case "", ".":
+			continue
+		case "..":
+			if descended {
+				return fmt.Errorf("symlink to '%s' has a '..' after the start of it, which isn't allowed", linkname)
+			}
+			current = filepath.Dir(current)
+			continue
+		}
+		descended = true
+		current = filepath.Join(current, component)
+
+		// Links to other links (like 'lib.so' -> 'lib.so.1') are fine, but
+		// links through them aren't
+		if i == len(components)-1 {
+			break
+		}
+		if info, err := os.Lstat(current); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("symlink to '%s' goes through another symlink, which isn't allowed", linkname)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("making parent directories: %w", err)
+	}
+	return os.Symlink(linkname, target)
+}
+
+// makeArchiveHardlink creates a hardlink from an archive, the target of which
+// has already been checked to be inside destDir.
+func makeArchiveHardlink(destDir string, target string, linkTarget string) error {
+	if err := checkArchiveParents(destDir, target); err != nil {
+		return err
+	}
+	if err := checkArchiveParents(destDir, linkTarget); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("making parent directories: %w", err)
+	}
+	return os.Link(linkTarget, target)
+}
+
+// zstdDecompress streams the input through the zstd CLI. The returned function
+// must be called once the output has been read, to wait for zstd to exit.
+func zstdDecompress(input io.Reader, out *message.Group) (io.Reader, func() error, error) {
+	versionOutput, err := exec.Command("zstd", "--version").CombinedOutput()
+	if err != nil {
+		out.Debugf("%s: %s", err.Error(), string(versionOutput))
+		return nil, nil, errors.New("zstd does not seem to be available on your PATH, so cannot unpack .tar.zst archives")
+	}
+
+	var stderr strings.Builder
+	zstdCmd := exec.Command("zstd", "--decompress", "--stdout")
+	zstdCmd.Stdin = input
+	zstdCmd.Stderr = &stderr
+	stdout, err := zstdCmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, fmt.Errorf("connecting to zstd output: %w", err)
+	}
+	if err := zstdCmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("starting zstd: %w", err)
+	}
+
+	wait := func() error {
+		// Whatever's left needs draining, or zstd could block forever writing
+		// it
+		if _, err := io.Copy(io.Discard, stdout); err != nil {
+			return fmt.Errorf("draining zstd output: %w", err)
+		}
+		if err := zstdCmd.Wait(); err != nil {
+			return fmt.Errorf("decompressing with zstd: exec error '%w', with output: %s", err, stderr.String())
+		}
+		return nil
+	}
+
+	return stdout, wait, nil
+}
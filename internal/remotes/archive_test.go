@@ -0,0 +1,254 @@
+package remotes
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/opensourcecorp/vdm/internal/vdmspec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ulikunitz/xz"
+)
+
+// testArchiveEntry is a single entry to put in a test archive. Entries with a
+// linkname are symlinks, and entries whose name ends in a slash are
+// directories.
+type testArchiveEntry struct {
+	name     string
+	contents string
+	linkname string
+}
+
+var testArchiveEntries = []testArchiveEntry{
+	{name: "project-v1/"},
+	{name: "project-v1/README.md", contents: "readme"},
+	{name: "project-v1/src/main.sh", contents: "echo hi"},
+	{name: "project-v1/src/link.sh", linkname: "main.sh"},
+}
+
+func newTestTar(t *testing.T, entries []testArchiveEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tarWriter := tar.NewWriter(&buf)
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Mode: 0644, Size: int64(len(entry.contents)), Typeflag: tar.TypeReg}
+		switch {
+		case entry.linkname != "":
+			header.Typeflag, header.Linkname, header.Size = tar.TypeSymlink, entry.linkname, 0
+		case entry.name[len(entry.name)-1] == '/':
+			header.Typeflag, header.Mode = tar.TypeDir, 0755
+		}
+		require.NoError(t, tarWriter.WriteHeader(header))
+		_, err := tarWriter.Write([]byte(entry.contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tarWriter.Close())
+
+	return buf.Bytes()
+}
+
+func newTestZip(t *testing.T, entries []testArchiveEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zipWriter := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		header := &zip.FileHeader{Name: entry.name, Method: zip.Deflate}
+		contents := entry.contents
+		switch {
+		case entry.linkname != "":
+			header.SetMode(0777 | os.ModeSymlink)
+			contents = entry.linkname
+		case entry.name[len(entry.name)-1] == '/':
+			header.SetMode(0755 | os.ModeDir)
+		default:
+			header.SetMode(0644)
+		}
+		writer, err := zipWriter.CreateHeader(header)
+		require.NoError(t, err)
+		_, err = writer.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zipWriter.Close())
+
+	return buf.Bytes()
+}
+
+// compressWithCLI runs the provided compression tool over the input, skipping
+// the test if it's not available.
+func compressWithCLI(t *testing.T, tool string, input []byte) []byte {
+	t.Helper()
+
+	if _, err := exec.LookPath(tool); err != nil {
+		t.Skipf("%s not available on PATH", tool)
+	}
+	cmd := exec.Command(tool, "--stdout")
+	cmd.Stdin = bytes.NewReader(input)
+	output, err := cmd.Output()
+	require.NoError(t, err)
+
+	return output
+}
+
+func writeTestArchive(t *testing.T, contents []byte) string {
+	t.Helper()
+
+	archivePath := filepath.Join(t.TempDir(), "archive")
+	require.NoError(t, os.WriteFile(archivePath, contents, 0644))
+
+	return archivePath
+}
+
+func TestExtractArchive(t *testing.T) {
+	tarContents := newTestTar(t, testArchiveEntries)
+
+	var gzipBuf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipBuf)
+	_, err := gzipWriter.Write(tarContents)
+	require.NoError(t, err)
+	require.NoError(t, gzipWriter.Close())
+
+	var xzBuf bytes.Buffer
+	xzWriter, err := xz.NewWriter(&xzBuf)
+	require.NoError(t, err)
+	_, err = xzWriter.Write(tarContents)
+	require.NoError(t, err)
+	require.NoError(t, xzWriter.Close())
+
+	for _, tc := range []struct {
+		format   string
+		contents func(t *testing.T) []byte
+	}{
+		{format: vdmspec.ArchiveTar, contents: func(*testing.T) []byte { return tarContents }},
+		{format: vdmspec.ArchiveTarGz, contents: func(*testing.T) []byte { return gzipBuf.Bytes() }},
+		{format: vdmspec.ArchiveTarXz, contents: func(*testing.T) []byte { return xzBuf.Bytes() }},
+		{format: vdmspec.ArchiveTarBz2, contents: func(t *testing.T) []byte { return compressWithCLI(t, "bzip2", tarContents) }},
+		{format: vdmspec.ArchiveTarZst, contents: func(t *testing.T) []byte { return compressWithCLI(t, "zstd", tarContents) }},
+		{format: vdmspec.ArchiveZip, contents: func(t *testing.T) []byte { return newTestZip(t, testArchiveEntries) }},
+	} {
+		t.Run(tc.format, func(t *testing.T) {
+			archivePath := writeTestArchive(t, tc.contents(t))
+
+			t.Run("unpacks everything", func(t *testing.T) {
+				destDir := filepath.Join(t.TempDir(), "dest")
+				require.NoError(t, extractArchive(vdmspec.Remote{}, archivePath, destDir, tc.format, nil))
+
+				got, err := os.ReadFile(filepath.Join(destDir, "project-v1", "src", "main.sh"))
+				require.NoError(t, err)
+				assert.Equal(t, "echo hi", string(got))
+
+				linkname, err := os.Readlink(filepath.Join(destDir, "project-v1", "src", "link.sh"))
+				require.NoError(t, err)
+				assert.Equal(t, "main.sh", linkname)
+			})
+
+			t.Run("strips leading components", func(t *testing.T) {
+				destDir := filepath.Join(t.TempDir(), "dest")
+				remote := vdmspec.Remote{StripComponents: 1}
+				require.NoError(t, extractArchive(remote, archivePath, destDir, tc.format, nil))
+
+				assert.FileExists(t, filepath.Join(destDir, "README.md"))
+				assert.FileExists(t, filepath.Join(destDir, "src", "main.sh"))
+				assert.NoDirExists(t, filepath.Join(destDir, "project-v1"))
+			})
+		})
+	}
+}
+
+func TestExtractArchiveRejectsEscapes(t *testing.T) {
+	for name, entries := range map[string][]testArchiveEntry{
+		"path traversal":                  {{name: "../evil.sh", contents: "evil"}},
+		"nested path traversal":           {{name: "project/../../evil.sh", contents: "evil"}},
+		"absolute path":                   {{name: "/tmp/evil.sh", contents: "evil"}},
+		"symlink escaping the local path": {{name: "escape", linkname: "../.."}},
+		"absolute symlink":                {{name: "escape", linkname: "/etc"}},
+		"writing through a symlink": {
+			{name: "sub/"},
+			{name: "link", linkname: "sub"},
+			{name: "link/evil.sh", contents: "evil"},
+		},
+		"writing over a symlink": {
+			{name: "sub/"},
+			{name: "link", linkname: "sub/evil.sh"},
+			{name: "link", contents: "evil"},
+		},
+		"symlink through another symlink": {
+			{name: "l2", linkname: "."},
+			{name: "l1", linkname: "l2/../evil.sh"},
+			{name: "l1", contents: "evil"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			for format, contents := range map[string][]byte{
+				vdmspec.ArchiveTar: newTestTar(t, entries),
+				vdmspec.ArchiveZip: newTestZip(t, entries),
+			} {
+				archivePath := writeTestArchive(t, contents)
+				parentDir := t.TempDir()
+				destDir := filepath.Join(parentDir, "dest")
+
+				err := extractArchive(vdmspec.Remote{}, archivePath, destDir, format, nil)
+				assert.Error(t, err, format)
+				assert.NoFileExists(t, filepath.Join(parentDir, "evil.sh"), format)
+				assert.NoFileExists(t, filepath.Join(destDir, "sub", "evil.sh"), format)
+			}
+		})
+	}
+}
+
+func TestDetectArchiveFormat(t *testing.T) {
+	for _, tc := range []struct {
+		remote      vdmspec.Remote
+		contentType string
+		want        string
+	}{
+		{remote: vdmspec.Remote{Remote: "https://example.com/release.tar.gz"}, want: vdmspec.ArchiveTarGz},
+		{remote: vdmspec.Remote{Remote: "https://example.com/release.TGZ?download=1"}, want: vdmspec.ArchiveTarGz},
+		{remote: vdmspec.Remote{Remote: "https://example.com/release.tar.zst"}, want: vdmspec.ArchiveTarZst},
+		{remote: vdmspec.Remote{Remote: "https://example.com/release.zip"}, want: vdmspec.ArchiveZip},
+		{remote: vdmspec.Remote{Remote: "https://example.com/download"}, contentType: "application/zip", want: vdmspec.ArchiveZip},
+		{remote: vdmspec.Remote{Remote: "https://example.com/http.proto"}, contentType: "text/plain; charset=utf-8", want: ""},
+		{remote: vdmspec.Remote{Remote: "https://example.com/download", Archive: vdmspec.ArchiveTarXz}, want: vdmspec.ArchiveTarXz},
+		{remote: vdmspec.Remote{Remote: "https://example.com/release.zip", Archive: vdmspec.ArchiveNone}, want: ""},
+	} {
+		assert.Equal(t, tc.want, detectArchiveFormat(tc.remote, tc.contentType), tc.remote.Remote)
+	}
+}
+
+func TestSyncFileArchive(t *testing.T) {
+	tarContents := newTestTar(t, testArchiveEntries)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		gzipWriter := gzip.NewWriter(w)
+		_, err := io.Copy(gzipWriter, bytes.NewReader(tarContents))
+		require.NoError(t, err)
+		require.NoError(t, gzipWriter.Close())
+	}))
+	t.Cleanup(server.Close)
+
+	remote := vdmspec.Remote{
+		Type:            vdmspec.FileType,
+		Remote:          server.URL + "/project-v1.tar.gz",
+		LocalPath:       filepath.Join(t.TempDir(), "deps", "project"),
+		StripComponents: 1,
+	}
+	result, err := SyncFile(remote, vdmspec.LockedRemote{}, nil, nil)
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.Locked.SHA256)
+
+	assert.FileExists(t, filepath.Join(remote.LocalPath, "README.md"))
+	assert.NoFileExists(t, remote.LocalPath+".vdm-archive")
+
+	manifest, err := remote.BuildManifest()
+	require.NoError(t, err)
+	assert.Contains(t, manifest, "src/main.sh")
+}
@@ -1,107 +1,276 @@
 package remotes
 
 import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/opensourcecorp/vdm/internal/message"
 	"github.com/opensourcecorp/vdm/internal/vdmspec"
 )
 
+// FileResult is the outcome of syncing a file remote.
+type FileResult struct {
+	// Locked records what was retrieved.
+	Locked vdmspec.LockedRemote
+	// Meta records what the server said about what was retrieved, so that it
+	// can be written to the remote's metafile.
+	Meta vdmspec.FileMeta
+	// Unchanged is set when the previously-retrieved file is still current, in
+	// which case it doesn't need replacing with anything that may have been
+	// written to the remote's local path, and Locked is the provided locked
+	// entry.
+	Unchanged bool
+}
+
 // SyncFile is the root of the sync operations for "file" remote types.
-func SyncFile(remote vdmspec.Remote) error {
-	fileExists, err := checkFileExists(remote)
+//
+// If previous is provided, it's what the server said about the file the last
+// time it was retrieved (and locked), and is used to make a conditional request
+// so that the server can say the file hasn't changed without sending it again.
+// Even if the server sends it anyway, a file with the same digest as before is
+// still reported as unchanged.
+//
+// If the file is an archive (see [vdmspec.Remote.Archive]), it's unpacked into
+// the local path, which becomes a directory, and any include & exclude patterns
+// are applied to what was unpacked.
+//
+// If the remote has a locked entry that the retrieved file doesn't match, the
+// remote has changed upstream since the lockfile was written, and an error is
+// returned instead of accepting the new contents. To accept them, provide an
+// empty locked entry, as when the remote is being updated.
+func SyncFile(remote vdmspec.Remote, locked vdmspec.LockedRemote, previous *vdmspec.FileMeta, out *message.Group) (FileResult, error) {
+	out.Infof("%s: Retrieving...", remote.OpMsg())
+	resp, err := retrieveFile(remote, previous, out)
 	if err != nil {
-		return fmt.Errorf("checking if file exists locally: %w", err)
+		return FileResult{}, fmt.Errorf("retrieving file: %w", err)
 	}
 
-	if !fileExists {
-		message.Infof("File '%s' does not exist locally, retrieving", remote.LocalPath)
-		err = retrieveFile(remote)
-		if err != nil {
-			return fmt.Errorf("retrieving file: %w", err)
+	if previous != nil && (resp.notModified || resp.digest == previous.SHA256) {
+		if locked.IsZero() {
+			return FileResult{}, fmt.Errorf("'%s' is unchanged upstream, but has no %s entry to keep", remote.Remote, vdmspec.LockFileName)
 		}
-	} else {
-		message.Infof("File '%s' already exists locally, skipping", remote.LocalPath)
+		out.Infof("%s: unchanged upstream since it was last retrieved", remote.OpMsg())
+		return FileResult{Locked: locked, Unchanged: true}, nil
+	}
+	if resp.notModified {
+		return FileResult{}, fmt.Errorf("server said '%s' is unchanged, but no conditional request was made", remote.Remote)
 	}
 
-	return nil
+	if locked.SHA256 != "" && locked.SHA256 != resp.digest {
+		return FileResult{}, fmt.Errorf(
+			"contents now have SHA-256 '%s', but %s has '%s', so the remote has changed upstream -- run 'vdm update %s' to accept the new contents",
+			resp.digest, vdmspec.LockFileName, locked.SHA256, remote.DisplayName(),
+		)
+	}
+
+	if format := detectArchiveFormat(remote, resp.contentType); format != "" {
+		if err := unpackFile(remote, format, out); err != nil {
+			return FileResult{}, fmt.Errorf("unpacking archive: %w", err)
+		}
+		if err := applyFilters(remote, out); err != nil {
+			return FileResult{}, err
+		}
+	} else if remote.HasFilters() {
+		out.Warnf("%s: has include/exclude patterns, but isn't an archive, so they don't apply", remote.OpMsg())
+	}
+
+	return FileResult{
+		Locked: vdmspec.LockedRemote{
+			Remote:    remote,
+			URL:       resp.finalURL,
+			SHA256:    resp.digest,
+			FetchedAt: time.Now().UTC().Truncate(time.Second),
+		},
+		Meta: vdmspec.FileMeta{
+			ETag:         resp.etag,
+			LastModified: resp.lastModified,
+			SHA256:       resp.digest,
+		},
+	}, nil
 }
 
-func checkFileExists(remote vdmspec.Remote) (bool, error) {
-	fullPath, err := filepath.Abs(remote.LocalPath)
-	if err != nil {
-		return false, fmt.Errorf("determining abspath for file '%s': %w", remote.LocalPath, err)
+// unpackFile replaces the archive that was retrieved to the remote's local path
+// with its unpacked contents.
+func unpackFile(remote vdmspec.Remote, format string, out *message.Group) error {
+	archivePath := remote.LocalPath + ".vdm-archive"
+	if err := os.Rename(remote.LocalPath, archivePath); err != nil {
+		return fmt.Errorf("moving archive out of the way: %w", err)
 	}
 
-	_, err = os.Stat(remote.LocalPath)
-	if errors.Is(err, os.ErrNotExist) {
-		return false, nil
-	} else if err != nil {
-		return false, fmt.Errorf("couldn't check if %s exists at '%s': %w", remote.LocalPath, fullPath, err)
+	if err := extractArchive(remote, archivePath, remote.LocalPath, format, out); err != nil {
+		return errors.Join(err, os.RemoveAll(remote.LocalPath), os.Remove(archivePath))
 	}
 
-	return true, nil
+	if err := os.Remove(archivePath); err != nil {
+		return fmt.Errorf("removing unpacked archive: %w", err)
+	}
+
+	return nil
 }
 
-func retrieveFile(remote vdmspec.Remote) (err error) {
-	resp, err := http.Get(remote.Remote)
+// fileResponse is what the server said when a remote file was retrieved.
+type fileResponse struct {
+	// notModified is set if the server said that the file hasn't changed since
+	// it was last retrieved, in which case nothing else is set.
+	notModified  bool
+	finalURL     string
+	digest       string
+	etag         string
+	lastModified string
+	contentType  string
+}
+
+// retrieveFile downloads the remote file to its local path, hashing it along the
+// way. If previous is provided, the request is made conditional on the file
+// having changed since then.
+func retrieveFile(remote vdmspec.Remote, previous *vdmspec.FileMeta, out *message.Group) (result fileResponse, err error) {
+	req, err := http.NewRequest(http.MethodGet, remote.Remote, nil)
 	if err != nil {
-		return fmt.Errorf("retrieving remote file '%s': %w", remote.Remote, err)
+		return fileResponse{}, fmt.Errorf("building request for remote file '%s': %w", remote.Remote, err)
+	}
+	if previous != nil {
+		if previous.ETag != "" {
+			out.Debugf("%s: sending If-None-Match: %s", remote.OpMsg(), previous.ETag)
+			req.Header.Set("If-None-Match", previous.ETag)
+		}
+		if previous.LastModified != "" {
+			out.Debugf("%s: sending If-Modified-Since: %s", remote.OpMsg(), previous.LastModified)
+			req.Header.Set("If-Modified-Since", previous.LastModified)
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fileResponse{}, fmt.Errorf("retrieving remote file '%s': %w", remote.Remote, err)
 	}
 	defer func() {
 		if closeErr := resp.Body.Close(); closeErr != nil {
-			err = errors.Join(fmt.Errorf("closing response body after remote file '%s' retrieval: %w", remote.Remote, err))
+			err = errors.Join(err, fmt.Errorf("closing response body after remote file '%s' retrieval: %w", remote.Remote, closeErr))
 		}
 	}()
 
+	if resp.StatusCode == http.StatusNotModified {
+		return fileResponse{notModified: true}, nil
+	}
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unsuccessful status code '%d' from server when retrieving remote file '%s'", resp.StatusCode, remote.Remote)
+		return fileResponse{}, fmt.Errorf("unsuccessful status code '%d' from server when retrieving remote file '%s'", resp.StatusCode, remote.Remote)
+	}
+
+	err = ensureParentDirs(remote.LocalPath, out)
+	if err != nil {
+		return fileResponse{}, fmt.Errorf("creating parent directories for file: %w", err)
 	}
 
-	err = ensureParentDirs(remote.LocalPath)
+	sha256Digest, sha512Digest, err := writeFile(remote, resp.Body, out)
 	if err != nil {
-		return fmt.Errorf("creating parent directories for file: %w", err)
+		return fileResponse{}, err
 	}
 
+	if err := checkPinnedDigests(remote, sha256Digest, sha512Digest); err != nil {
+		out.Debugf("%s: removing '%s', since it didn't match its pinned digest", remote.OpMsg(), remote.LocalPath)
+		if removeErr := os.Remove(remote.LocalPath); removeErr != nil {
+			return fileResponse{}, errors.Join(err, fmt.Errorf("removing rejected file '%s': %w", remote.LocalPath, removeErr))
+		}
+		return fileResponse{}, err
+	}
+
+	return fileResponse{
+		finalURL:     resp.Request.URL.String(),
+		digest:       sha256Digest,
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+		contentType:  resp.Header.Get("Content-Type"),
+	}, nil
+}
+
+// writeFile writes the contents of the reader to the remote's local path,
+// hashing them along the way, and returns their hex-encoded SHA-256 & SHA-512
+// digests.
+func writeFile(remote vdmspec.Remote, contents io.Reader, out *message.Group) (sha256Digest string, sha512Digest string, err error) {
 	// Note: I would normally use os.WriteFile() using the returned bytes
 	// directly, but the internet says this os.Create()/io.Copy() approach
 	// appears to be idiomatic
 	outFile, err := os.Create(remote.LocalPath)
 	if err != nil {
-		return fmt.Errorf("creating landing file '%s' for remote file: %w", remote.LocalPath, err)
+		return "", "", fmt.Errorf("creating landing file '%s' for remote file: %w", remote.LocalPath, err)
 	}
 	defer func() {
 		if closeErr := outFile.Close(); closeErr != nil {
-			err = errors.Join(fmt.Errorf("closing local file '%s' after remote file '%s' retrieval: %w", remote.LocalPath, remote.Remote, err))
+			err = errors.Join(err, fmt.Errorf("closing local file '%s' after remote file '%s' retrieval: %w", remote.LocalPath, remote.Remote, closeErr))
 		}
 	}()
 
-	bytesWritten, err := io.Copy(outFile, resp.Body)
+	sha256Hasher, sha512Hasher := sha256.New(), sha512.New()
+	bytesWritten, err := io.Copy(io.MultiWriter(outFile, sha256Hasher, sha512Hasher), contents)
 	if err != nil {
-		return fmt.Errorf("copying HTTP response to disk: ")
+		return "", "", fmt.Errorf("copying HTTP response to disk: %w", err)
 	}
-	message.Debugf("wrote %d bytes to '%s'", bytesWritten, remote.LocalPath)
+	out.Debugf("wrote %d bytes to '%s'", bytesWritten, remote.LocalPath)
 
-	return nil
+	return hex.EncodeToString(sha256Hasher.Sum(nil)), hex.EncodeToString(sha512Hasher.Sum(nil)), nil
+}
+
+// checkPinnedDigests returns an error if the provided digests don't match those
+// that the remote is pinned to, if any.
+func checkPinnedDigests(remote vdmspec.Remote, sha256Digest string, sha512Digest string) error {
+	var allErrors []error
+	if remote.SHA256 != "" && !strings.EqualFold(remote.SHA256, sha256Digest) {
+		allErrors = append(allErrors, fmt.Errorf("contents of '%s' have SHA-256 '%s', but the spec file pins it to '%s'", remote.Remote, sha256Digest, remote.SHA256))
+	}
+	if remote.SHA512 != "" && !strings.EqualFold(remote.SHA512, sha512Digest) {
+		allErrors = append(allErrors, fmt.Errorf("contents of '%s' have SHA-512 '%s', but the spec file pins it to '%s'", remote.Remote, sha512Digest, remote.SHA512))
+	}
+
+	return errors.Join(allErrors...)
+}
+
+// ResolveFileDigest retrieves the remote file without writing it to disk, and
+// returns the final URL it was retrieved from along with the SHA-256 digest of
+// its contents.
+func ResolveFileDigest(remote vdmspec.Remote) (finalURL string, digest string, err error) {
+	resp, err := http.Get(remote.Remote)
+	if err != nil {
+		return "", "", fmt.Errorf("retrieving remote file '%s': %w", remote.Remote, err)
+	}
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			err = errors.Join(err, fmt.Errorf("closing response body after remote file '%s' retrieval: %w", remote.Remote, closeErr))
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("unsuccessful status code '%d' from server when retrieving remote file '%s'", resp.StatusCode, remote.Remote)
+	}
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, resp.Body); err != nil {
+		return "", "", fmt.Errorf("hashing HTTP response: %w", err)
+	}
+
+	return resp.Request.URL.String(), hex.EncodeToString(hasher.Sum(nil)), nil
 }
 
-func ensureParentDirs(path string) error {
+func ensureParentDirs(path string, out *message.Group) error {
 	fullPath, err := filepath.Abs(path)
 	if err != nil {
 		return fmt.Errorf("determining abspath for file '%s': %w", path, err)
 	}
-	message.Debugf("absolute filepath for '%s' determined to be '%s'", path, fullPath)
+	out.Debugf("absolute filepath for '%s' determined to be '%s'", path, fullPath)
 	dir := filepath.Dir(fullPath)
 	err = os.MkdirAll(dir, os.ModePerm)
 	if err != nil {
 		return fmt.Errorf("making directories: %w", err)
 	}
-	message.Debugf("created director(ies): %s", dir)
+	out.Debugf("created director(ies): %s", dir)
 
 	return nil
 }
@@ -1 +1,183 @@
 package remotes
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opensourcecorp/vdm/internal/vdmspec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testFileContents = "some file contents"
+
+var testFileSHA256 = func() string {
+	sum := sha256.Sum256([]byte(testFileContents))
+	return hex.EncodeToString(sum[:])
+}()
+
+// newTestFileServer returns a server that responds to every request with
+// [testFileContents].
+func newTestFileServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, err := w.Write([]byte(testFileContents))
+		require.NoError(t, err)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func getTestFileSpec(t *testing.T, server *httptest.Server) vdmspec.Remote {
+	return vdmspec.Remote{
+		Type:      vdmspec.FileType,
+		Remote:    server.URL + "/some.file",
+		LocalPath: filepath.Join(t.TempDir(), "deps", "some.file"),
+	}
+}
+
+// newTestConditionalFileServer returns a server that responds with whatever
+// contents are currently set, along with an ETag for them, and honors
+// If-None-Match. The returned counter is how many times the server actually
+// sent the contents.
+func newTestConditionalFileServer(t *testing.T, contents *string) (*httptest.Server, *int) {
+	t.Helper()
+
+	var sent int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sum := sha256.Sum256([]byte(*contents))
+		etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		sent++
+		_, err := w.Write([]byte(*contents))
+		require.NoError(t, err)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &sent
+}
+
+func TestSyncFile(t *testing.T) {
+	server := newTestFileServer(t)
+
+	t.Run("retrieves file and resolves its digest", func(t *testing.T) {
+		remote := getTestFileSpec(t, server)
+		result, err := SyncFile(remote, vdmspec.LockedRemote{}, nil, nil)
+		require.NoError(t, err)
+
+		got, err := os.ReadFile(remote.LocalPath)
+		require.NoError(t, err)
+		assert.Equal(t, testFileContents, string(got))
+
+		assert.False(t, result.Unchanged)
+		assert.Equal(t, remote.Remote, result.Locked.URL)
+		assert.Equal(t, testFileSHA256, result.Locked.SHA256)
+		assert.Equal(t, testFileSHA256, result.Meta.SHA256)
+	})
+
+	t.Run("fails when the locked digest doesn't match", func(t *testing.T) {
+		remote := getTestFileSpec(t, server)
+		_, err := SyncFile(remote, vdmspec.LockedRemote{Remote: remote, SHA256: "abc123"}, nil, nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "vdm update")
+	})
+
+	t.Run("retrieves file matching its pinned digests", func(t *testing.T) {
+		remote := getTestFileSpec(t, server)
+		remote.SHA256 = strings.ToUpper(testFileSHA256)
+		sum := sha512.Sum512([]byte(testFileContents))
+		remote.SHA512 = hex.EncodeToString(sum[:])
+
+		_, err := SyncFile(remote, vdmspec.LockedRemote{}, nil, nil)
+		require.NoError(t, err)
+		assert.FileExists(t, remote.LocalPath)
+	})
+
+	t.Run("rejects & removes file that doesn't match its pinned digest", func(t *testing.T) {
+		remote := getTestFileSpec(t, server)
+		remote.SHA256 = strings.Repeat("0", 64)
+
+		_, err := SyncFile(remote, vdmspec.LockedRemote{}, nil, nil)
+		assert.Error(t, err)
+
+		_, err = os.Stat(remote.LocalPath)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("same contents as before are unchanged, even without a conditional request", func(t *testing.T) {
+		remote := getTestFileSpec(t, server)
+		locked := vdmspec.LockedRemote{Remote: remote, SHA256: testFileSHA256}
+		result, err := SyncFile(remote, locked, &vdmspec.FileMeta{SHA256: testFileSHA256}, nil)
+		require.NoError(t, err)
+
+		assert.True(t, result.Unchanged)
+		assert.Equal(t, locked, result.Locked)
+	})
+}
+
+func TestSyncFileConditional(t *testing.T) {
+	contents := "first contents"
+	server, sent := newTestConditionalFileServer(t, &contents)
+	remote := getTestFileSpec(t, server)
+
+	first, err := SyncFile(remote, vdmspec.LockedRemote{}, nil, nil)
+	require.NoError(t, err)
+	require.NotEmpty(t, first.Meta.ETag)
+	require.Equal(t, 1, *sent)
+
+	t.Run("server saying the file is unchanged skips retrieving it again", func(t *testing.T) {
+		result, err := SyncFile(remote, first.Locked, &first.Meta, nil)
+		require.NoError(t, err)
+
+		assert.True(t, result.Unchanged)
+		assert.Equal(t, first.Locked, result.Locked)
+		assert.Equal(t, 1, *sent)
+	})
+
+	t.Run("changed file doesn't match its locked entry", func(t *testing.T) {
+		contents = "second contents"
+		_, err := SyncFile(remote, first.Locked, &first.Meta, nil)
+		assert.Error(t, err)
+		assert.Equal(t, 2, *sent)
+	})
+
+	t.Run("changed file is retrieved again when updating", func(t *testing.T) {
+		result, err := SyncFile(remote, vdmspec.LockedRemote{}, &first.Meta, nil)
+		require.NoError(t, err)
+
+		assert.False(t, result.Unchanged)
+		assert.NotEqual(t, first.Meta.ETag, result.Meta.ETag)
+		assert.NotEqual(t, first.Locked.SHA256, result.Locked.SHA256)
+		assert.Equal(t, 3, *sent)
+
+		got, err := os.ReadFile(remote.LocalPath)
+		require.NoError(t, err)
+		assert.Equal(t, "second contents", string(got))
+	})
+}
+
+func TestResolveFileDigest(t *testing.T) {
+	server := newTestFileServer(t)
+	remote := getTestFileSpec(t, server)
+
+	finalURL, digest, err := ResolveFileDigest(remote)
+	require.NoError(t, err)
+	assert.Equal(t, remote.Remote, finalURL)
+	assert.Equal(t, testFileSHA256, digest)
+
+	_, err = os.Stat(remote.LocalPath)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,102 @@
+package remotes
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/opensourcecorp/vdm/internal/message"
+	"github.com/opensourcecorp/vdm/internal/vdmspec"
+)
+
+// applyFilters removes every file under the remote's local path that its
+// include & exclude patterns don't keep, along with any directories left empty
+// by doing so.
+func applyFilters(remote vdmspec.Remote, out *message.Group) error {
+	if !remote.HasFilters() {
+		return nil
+	}
+
+	var removed int
+	var dirs []string
+	err := filepath.WalkDir(remote.LocalPath, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(remote.LocalPath, filePath)
+		if err != nil {
+			return fmt.Errorf("determining path of '%s' relative to '%s': %w", filePath, remote.LocalPath, err)
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		if d.IsDir() {
+			if relPath != "." {
+				dirs = append(dirs, filePath)
+			}
+			return nil
+		}
+
+		if keepPath(remote, relPath) {
+			return nil
+		}
+
+		out.Debugf("%s: filtering out '%s'", remote.OpMsg(), relPath)
+		if err := os.Remove(filePath); err != nil {
+			return fmt.Errorf("removing filtered-out file '%s': %w", filePath, err)
+		}
+		removed++
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("filtering local path '%s': %w", remote.LocalPath, err)
+	}
+
+	// Deepest directories first, so that parents are empty by the time they're
+	// checked
+	sort.Sort(sort.Reverse(sort.StringSlice(dirs)))
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return fmt.Errorf("checking if directory '%s' is empty: %w", dir, err)
+		}
+		if len(entries) > 0 {
+			continue
+		}
+		if err := os.Remove(dir); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("removing emptied directory '%s': %w", dir, err)
+		}
+	}
+
+	out.Infof("%s: Filtered out %d file(s).", remote.OpMsg(), removed)
+	return nil
+}
+
+// keepPath reports whether the remote's include & exclude patterns keep the
+// file at the provided slash-separated path, relative to the local path.
+func keepPath(remote vdmspec.Remote, relPath string) bool {
+	if len(remote.Include) > 0 && !matchesAny(remote.Include, relPath) {
+		return false
+	}
+	return !matchesAny(remote.Exclude, relPath)
+}
+
+// matchesAny reports whether any of the patterns match the path, or any of the
+// directories it's in.
+func matchesAny(patterns []string, relPath string) bool {
+	for candidate := relPath; candidate != "." && candidate != "/"; candidate = path.Dir(candidate) {
+		for _, pattern := range patterns {
+			// Patterns were validated with the spec, so errors can't happen
+			if matched, _ := doublestar.Match(pattern, candidate); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
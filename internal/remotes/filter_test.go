@@ -0,0 +1,69 @@
+package remotes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opensourcecorp/vdm/internal/vdmspec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyFilters(t *testing.T) {
+	files := []string{
+		"README.md",
+		"go.mod",
+		"pkg/thing.go",
+		"pkg/thing_test.go",
+		"pkg/nested/other.go",
+		"docs/index.md",
+		".github/workflows/ci.yaml",
+	}
+
+	for _, tc := range []struct {
+		name    string
+		include []string
+		exclude []string
+		want    []string
+	}{
+		{
+			name:    "exclude only",
+			exclude: []string{"docs", ".github", "**/*_test.go"},
+			want:    []string{"README.md", "go.mod", "pkg/nested/other.go", "pkg/thing.go"},
+		},
+		{
+			name:    "include only",
+			include: []string{"pkg/**/*.go", "go.mod"},
+			want:    []string{"go.mod", "pkg/nested/other.go", "pkg/thing.go", "pkg/thing_test.go"},
+		},
+		{
+			name:    "include then exclude",
+			include: []string{"pkg"},
+			exclude: []string{"**/*_test.go", "pkg/nested"},
+			want:    []string{"pkg/thing.go"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			remote := vdmspec.Remote{LocalPath: t.TempDir(), Include: tc.include, Exclude: tc.exclude}
+			for _, file := range files {
+				filePath := filepath.Join(remote.LocalPath, filepath.FromSlash(file))
+				require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+				require.NoError(t, os.WriteFile(filePath, []byte(file), 0644))
+			}
+
+			require.NoError(t, applyFilters(remote, nil))
+
+			manifest, err := remote.BuildManifest()
+			require.NoError(t, err)
+			var got []string
+			for file := range manifest {
+				got = append(got, file)
+			}
+			assert.ElementsMatch(t, tc.want, got)
+
+			// Directories emptied by filtering shouldn't be left behind
+			assert.NoDirExists(t, filepath.Join(remote.LocalPath, "docs"))
+		})
+	}
+}
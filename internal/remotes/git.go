@@ -5,73 +5,449 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/opensourcecorp/vdm/internal/message"
 	"github.com/opensourcecorp/vdm/internal/vdmspec"
 )
 
-// SyncGit is the root of the sync operations for "git" remote types.
-func SyncGit(remote vdmspec.Remote) error {
-	err := gitClone(remote)
+// SyncGit is the root of the sync operations for "git" remote types. If the
+// remote has a locked entry, the locked commit is checked out instead of
+// resolving the remote's version. The returned entry records the commit that
+// was actually checked out.
+//
+// If the remote has a subdir set, only that directory is checked out (using a
+// partial clone where the server supports it), and its contents are placed at
+// the local path instead of the whole repository's. Any include & exclude
+// patterns are applied to what's placed there.
+//
+// If the remote's version is a semver range constraint, and there's no locked
+// commit to honor, it's first resolved to the newest tag on the remote that
+// satisfies it. The tag is recorded in the returned entry alongside its commit.
+func SyncGit(remote vdmspec.Remote, locked vdmspec.LockedRemote, out *message.Group) (vdmspec.LockedRemote, error) {
+	target := remote
+	var tag string
+	if remote.IsVersionConstraint() {
+		tag = locked.Tag
+		if locked.Commit == "" {
+			var err error
+			tag, err = ResolveGitConstraint(remote)
+			if err != nil {
+				return vdmspec.LockedRemote{}, fmt.Errorf("resolving version constraint: %w", err)
+			}
+			out.Infof("%s: Version constraint resolved to tag '%s'", remote.OpMsg(), tag)
+			target.Version = tag
+		}
+	}
+
+	resolved, err := syncGitRevision(target, locked, out)
+	if err != nil {
+		return vdmspec.LockedRemote{}, err
+	}
+	resolved.Remote = remote
+	resolved.Tag = tag
+
+	return resolved, nil
+}
+
+// syncGitRevision retrieves the git remote at its version (which can't be a
+// version constraint), or at the locked commit if there is one.
+func syncGitRevision(remote vdmspec.Remote, locked vdmspec.LockedRemote, out *message.Group) (vdmspec.LockedRemote, error) {
+	if HasKeptGitDir(remote) {
+		out.Infof("%s: Updating existing clone in place...", remote.OpMsg())
+		if err := gitUpdate(remote, locked.Commit, out); err != nil {
+			return vdmspec.LockedRemote{}, fmt.Errorf("updating existing clone: %w", err)
+		}
+		return resolveGitLock(remote, out)
+	}
+
+	// A subdir gets cloned next to the local path, and then moved into place
+	cloneRemote := remote
+	if remote.Subdir != "" {
+		cloneRemote.LocalPath = remote.LocalPath + ".vdm-clone"
+	}
+
+	// A shallow clone is only enough if there's no specific revision to find
+	shallow := remote.Version == "latest" && locked.Commit == ""
+	err := gitClone(cloneRemote, shallow, out)
 	if err != nil {
-		return fmt.Errorf("cloing remote: %w", err)
+		return vdmspec.LockedRemote{}, fmt.Errorf("cloing remote: %w", err)
 	}
 
-	if remote.Version != "latest" {
-		message.Infof("%s: Setting specified version...", remote.OpMsg())
-		checkoutCmd := exec.Command("git", "-C", remote.LocalPath, "checkout", remote.Version)
+	revision := remote.Version
+	if locked.Commit != "" {
+		out.Debugf("%s: honoring commit '%s' from %s", remote.OpMsg(), locked.Commit, vdmspec.LockFileName)
+		revision = locked.Commit
+	}
+	if !shallow {
+		out.Infof("%s: Setting specified version...", remote.OpMsg())
+		checkoutCmd := exec.Command("git", "-C", cloneRemote.LocalPath, "checkout", revision)
 		checkoutOutput, err := checkoutCmd.CombinedOutput()
 		if err != nil {
-			return fmt.Errorf("error checking out specified revision: exec error '%w', with output: %s", err, string(checkoutOutput))
+			return vdmspec.LockedRemote{}, fmt.Errorf("error checking out specified revision: exec error '%w', with output: %s", err, string(checkoutOutput))
+		}
+	} else if remote.Subdir != "" {
+		// Sparse clones are made without checking anything out, so the shallow
+		// clone's HEAD still needs to be
+		if _, err := runGit(cloneRemote.LocalPath, "checkout"); err != nil {
+			return vdmspec.LockedRemote{}, fmt.Errorf("checking out remote HEAD: %w", err)
+		}
+	}
+
+	resolved, err := resolveGitLock(cloneRemote, out)
+	if err != nil {
+		return vdmspec.LockedRemote{}, err
+	}
+	resolved.Remote = remote
+
+	if remote.Subdir != "" {
+		if err := placeGitSubdir(cloneRemote.LocalPath, remote, out); err != nil {
+			return vdmspec.LockedRemote{}, err
+		}
+		if err := applyFilters(remote, out); err != nil {
+			return vdmspec.LockedRemote{}, err
 		}
+		return resolved, nil
+	}
+
+	if remote.KeepGitDir {
+		out.Debugf("%s: keeping .git dir for local path '%s'", remote.OpMsg(), remote.LocalPath)
+		return resolved, nil
 	}
 
-	message.Debugf("removing .git dir for local path '%s'", remote.LocalPath)
+	out.Debugf("removing .git dir for local path '%s'", remote.LocalPath)
 	dotGitPath := filepath.Join(remote.LocalPath, ".git")
 	err = os.RemoveAll(dotGitPath)
 	if err != nil {
-		return fmt.Errorf("removing directory %s: %w", dotGitPath, err)
+		return vdmspec.LockedRemote{}, fmt.Errorf("removing directory %s: %w", dotGitPath, err)
+	}
+
+	if err := applyFilters(remote, out); err != nil {
+		return vdmspec.LockedRemote{}, err
+	}
+
+	return resolved, nil
+}
+
+// placeGitSubdir moves the remote's subdir out of the clone at clonePath and
+// into the remote's local path, and then removes the rest of the clone. The
+// subdir (and every directory on the way to it) must be a real directory, not
+// a symlink, which could point anywhere outside of the clone.
+func placeGitSubdir(clonePath string, remote vdmspec.Remote, out *message.Group) error {
+	subdirPath := clonePath
+	for _, component := range strings.Split(path.Clean(remote.Subdir), "/") {
+		subdirPath = filepath.Join(subdirPath, component)
+		info, err := os.Lstat(subdirPath)
+		if errors.Is(err, os.ErrNotExist) {
+			return errors.Join(
+				fmt.Errorf("subdir '%s' doesn't exist in the remote at version '%s'", remote.Subdir, remote.Version),
+				os.RemoveAll(clonePath),
+			)
+		} else if err != nil {
+			return errors.Join(fmt.Errorf("checking subdir '%s': %w", remote.Subdir, err), os.RemoveAll(clonePath))
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return errors.Join(fmt.Errorf("subdir '%s' in the remote is (or is inside) a symlink, which isn't allowed", remote.Subdir), os.RemoveAll(clonePath))
+		}
+		if !info.IsDir() {
+			return errors.Join(fmt.Errorf("subdir '%s' in the remote is not a directory", remote.Subdir), os.RemoveAll(clonePath))
+		}
+	}
+
+	out.Debugf("%s: moving subdir '%s' to local path '%s'", remote.OpMsg(), remote.Subdir, remote.LocalPath)
+	if err := os.Rename(subdirPath, remote.LocalPath); err != nil {
+		return errors.Join(fmt.Errorf("moving subdir '%s' to local path: %w", remote.Subdir, err), os.RemoveAll(clonePath))
+	}
+
+	if err := os.RemoveAll(clonePath); err != nil {
+		return fmt.Errorf("removing clone at '%s': %w", clonePath, err)
 	}
 
 	return nil
 }
 
-func checkGitAvailable() error {
+// resolveGitLock records the commit that the clone at the remote's local path
+// currently has checked out.
+func resolveGitLock(remote vdmspec.Remote, out *message.Group) (vdmspec.LockedRemote, error) {
+	commit, err := runGit(remote.LocalPath, "rev-parse", "HEAD")
+	if err != nil {
+		return vdmspec.LockedRemote{}, fmt.Errorf("resolving checked-out commit: %w", err)
+	}
+	out.Debugf("%s: resolved to commit '%s'", remote.OpMsg(), commit)
+
+	return vdmspec.LockedRemote{
+		Remote:    remote,
+		Commit:    commit,
+		FetchedAt: time.Now().UTC().Truncate(time.Second),
+	}, nil
+}
+
+// HasKeptGitDir reports whether the remote is set to keep its .git directory,
+// and a clone with one already exists at its local path. Such clones are
+// updated in place rather than being removed & re-cloned, so that any local
+// work in them isn't lost.
+func HasKeptGitDir(remote vdmspec.Remote) bool {
+	if !remote.KeepGitDir || (remote.Type != vdmspec.GitType && remote.Type != "") {
+		return false
+	}
+
+	_, err := os.Stat(filepath.Join(remote.LocalPath, ".git"))
+	return err == nil
+}
+
+func checkGitAvailable(out *message.Group) error {
 	cmd := exec.Command("git", "--version")
 	sysOutput, err := cmd.CombinedOutput()
 	if err != nil {
-		message.Debugf("%s: %s", err.Error(), string(sysOutput))
+		out.Debugf("%s: %s", err.Error(), string(sysOutput))
 		return errors.New("git does not seem to be available on your PATH, so cannot continue")
 	}
-	message.Debugf("git was found on PATH")
+	out.Debugf("git was found on PATH")
 	return nil
 }
 
-func gitClone(remote vdmspec.Remote) error {
-	err := checkGitAvailable()
+func gitClone(remote vdmspec.Remote, shallow bool, out *message.Group) error {
+	err := checkGitAvailable(out)
 	if err != nil {
 		return fmt.Errorf("remote '%s' is a git type, but git may not installed/available on PATH: %w", remote.Remote, err)
 	}
 
 	// If users want "latest", then we can just do a depth-one clone and
-	// skip the checkout operation. But if they want non-latest, we need the
-	// full history to be able to find a specified revision
-	var cloneCmdArgs []string
-	if remote.Version == "latest" {
-		message.Debugf("%s: version specified as 'latest', so making shallow clone and skipping separate checkout operation", remote.OpMsg())
-		cloneCmdArgs = []string{"clone", "--depth=1", remote.Remote, remote.LocalPath}
+	// skip the checkout operation. But if they want non-latest (or there's a
+	// locked commit to find), we need the full history to be able to find a
+	// specified revision
+	cloneCmdArgs := []string{"clone"}
+	if shallow {
+		out.Debugf("%s: version specified as 'latest', so making shallow clone and skipping separate checkout operation", remote.OpMsg())
+		cloneCmdArgs = append(cloneCmdArgs, "--depth=1")
 	} else {
-		message.Debugf("%s: version specified as NOT latest, so making regular clone and will make separate checkout operation", remote.OpMsg())
-		cloneCmdArgs = []string{"clone", remote.Remote, remote.LocalPath}
+		out.Debugf("%s: need a specific revision, so making regular clone and will make separate checkout operation", remote.OpMsg())
+	}
+
+	// If only a subdir is needed, skip retrieving the contents of anything else
+	// where the server supports it, and don't check anything out until the
+	// sparse checkout is set up
+	if remote.Subdir != "" {
+		out.Debugf("%s: only need subdir '%s', so making partial clone with sparse checkout", remote.OpMsg(), remote.Subdir)
+		cloneCmdArgs = append(cloneCmdArgs, "--filter=blob:none", "--no-checkout")
 	}
+	cloneCmdArgs = append(cloneCmdArgs, remote.Remote, remote.LocalPath)
 
-	message.Infof("%s: Retrieving...", remote.OpMsg())
+	out.Infof("%s: Retrieving...", remote.OpMsg())
 	cloneCmd := exec.Command("git", cloneCmdArgs...)
 	cloneOutput, err := cloneCmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("cloning remote: exec error '%w', with output: %s", err, string(cloneOutput))
 	}
 
+	if remote.Subdir != "" {
+		if _, err := runGit(remote.LocalPath, "sparse-checkout", "set", "--cone", remote.Subdir); err != nil {
+			return fmt.Errorf("setting up sparse checkout: %w", err)
+		}
+	}
+
 	return nil
 }
+
+// gitUpdate brings an existing clone to the remote's specified version (or the
+// locked commit, if provided) by fetching from the remote & checking out the
+// new revision. Git itself will refuse to check out over conflicting local
+// changes, which is what we want.
+func gitUpdate(remote vdmspec.Remote, lockedCommit string, out *message.Group) error {
+	err := checkGitAvailable(out)
+	if err != nil {
+		return fmt.Errorf("remote '%s' is a git type, but git may not installed/available on PATH: %w", remote.Remote, err)
+	}
+
+	// The remote itself may be what changed in the spec
+	if _, err := runGit(remote.LocalPath, "remote", "set-url", "origin", remote.Remote); err != nil {
+		return fmt.Errorf("setting origin URL: %w", err)
+	}
+
+	// A clone made for 'latest' is shallow, and so won't be able to find older
+	// revisions unless it gets the rest of its history
+	isShallow, err := runGit(remote.LocalPath, "rev-parse", "--is-shallow-repository")
+	if err != nil {
+		return fmt.Errorf("checking if clone is shallow: %w", err)
+	}
+	fetchArgs := []string{"fetch", "--tags", "origin"}
+	if isShallow == "true" && (remote.Version != "latest" || lockedCommit != "") {
+		out.Debugf("%s: existing clone is shallow, so fetching full history", remote.OpMsg())
+		fetchArgs = append(fetchArgs, "--unshallow")
+	}
+
+	out.Infof("%s: Fetching...", remote.OpMsg())
+	if _, err := runGit(remote.LocalPath, fetchArgs...); err != nil {
+		return fmt.Errorf("fetching from remote: %w", err)
+	}
+
+	switch {
+	case lockedCommit != "":
+		out.Debugf("%s: honoring commit '%s' from %s", remote.OpMsg(), lockedCommit, vdmspec.LockFileName)
+		if _, err := runGit(remote.LocalPath, "checkout", "--detach", lockedCommit); err != nil {
+			return fmt.Errorf("checking out locked commit: %w", err)
+		}
+
+	case remote.Version == "latest":
+		out.Debugf("%s: version specified as 'latest', so checking out remote HEAD", remote.OpMsg())
+		if _, err := runGit(remote.LocalPath, "fetch", "origin", "HEAD"); err != nil {
+			return fmt.Errorf("fetching remote HEAD: %w", err)
+		}
+		if _, err := runGit(remote.LocalPath, "checkout", "--detach", "FETCH_HEAD"); err != nil {
+			return fmt.Errorf("checking out remote HEAD: %w", err)
+		}
+
+	case isRemoteBranch(remote):
+		// Branches get fast-forwarded rather than reset, so local commits on
+		// them are never thrown away
+		out.Debugf("%s: version '%s' is a branch, so checking it out & fast-forwarding it", remote.OpMsg(), remote.Version)
+		if _, err := runGit(remote.LocalPath, "checkout", remote.Version); err != nil {
+			return fmt.Errorf("checking out branch: %w", err)
+		}
+		if _, err := runGit(remote.LocalPath, "merge", "--ff-only", "origin/"+remote.Version); err != nil {
+			return fmt.Errorf("fast-forwarding branch: %w", err)
+		}
+
+	default:
+		out.Infof("%s: Setting specified version...", remote.OpMsg())
+		if _, err := runGit(remote.LocalPath, "checkout", remote.Version); err != nil {
+			return fmt.Errorf("error checking out specified revision: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ResolveGitVersion asks the remote which commit its specified version
+// currently points to, without cloning anything. Versions that don't name a ref
+// on the remote (like commit hashes) can't move, and resolve to an empty
+// string. Version constraints are resolved to the newest tag that satisfies
+// them first.
+func ResolveGitVersion(remote vdmspec.Remote) (string, error) {
+	err := checkGitAvailable(nil)
+	if err != nil {
+		return "", fmt.Errorf("remote '%s' is a git type, but git may not installed/available on PATH: %w", remote.Remote, err)
+	}
+
+	version := remote.Version
+	if remote.IsVersionConstraint() {
+		version, err = ResolveGitConstraint(remote)
+		if err != nil {
+			return "", fmt.Errorf("resolving version constraint: %w", err)
+		}
+	}
+
+	pattern := version
+	if version == "latest" {
+		pattern = "HEAD"
+	}
+	refs, err := lsRemote(remote.Remote, pattern)
+	if err != nil {
+		return "", err
+	}
+
+	// Peeled tags point at the commit rather than the annotated tag object, so
+	// they take precedence
+	for _, ref := range []string{
+		"HEAD",
+		"refs/tags/" + version + "^{}",
+		"refs/tags/" + version,
+		"refs/heads/" + version,
+	} {
+		if commit, ok := refs[ref]; ok && (ref != "HEAD" || version == "latest") {
+			message.Debugf("%s: version resolved to '%s' via ref '%s'", remote.OpMsg(), commit, ref)
+			return commit, nil
+		}
+	}
+
+	message.Debugf("%s: version didn't match any ref on the remote, so assuming it's a commit", remote.OpMsg())
+	return "", nil
+}
+
+// ListGitTags asks the remote for all of its tags, without cloning anything.
+func ListGitTags(remote vdmspec.Remote) ([]string, error) {
+	err := checkGitAvailable(nil)
+	if err != nil {
+		return nil, fmt.Errorf("remote '%s' is a git type, but git may not installed/available on PATH: %w", remote.Remote, err)
+	}
+
+	refs, err := lsRemote(remote.Remote, "refs/tags/*")
+	if err != nil {
+		return nil, err
+	}
+
+	var tags []string
+	for ref := range refs {
+		// Annotated tags also list the commit they point to
+		if strings.HasSuffix(ref, "^{}") {
+			continue
+		}
+		tags = append(tags, strings.TrimPrefix(ref, "refs/tags/"))
+	}
+	sort.Strings(tags)
+
+	return tags, nil
+}
+
+// ResolveGitConstraint asks the remote for its tags, and returns the newest one
+// that satisfies the remote's version constraint.
+func ResolveGitConstraint(remote vdmspec.Remote) (string, error) {
+	tags, err := ListGitTags(remote)
+	if err != nil {
+		return "", err
+	}
+
+	tag, err := remote.NewestMatchingTag(tags)
+	if err != nil {
+		return "", err
+	}
+	message.Debugf("%s: version constraint resolved to tag '%s'", remote.OpMsg(), tag)
+
+	return tag, nil
+}
+
+// lsRemote lists the refs on a remote that match the provided patterns, mapped
+// to the commits they point to.
+func lsRemote(remoteURL string, patterns ...string) (map[string]string, error) {
+	lsRemoteArgs := append([]string{"ls-remote", remoteURL}, patterns...)
+	lsRemoteCmd := exec.Command("git", lsRemoteArgs...)
+	output, err := lsRemoteCmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing refs for remote '%s': exec error '%w'", remoteURL, err)
+	}
+
+	refs := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		refs[fields[1]] = fields[0]
+	}
+
+	return refs, nil
+}
+
+// isRemoteBranch reports whether the remote's version names a branch on the
+// clone's origin.
+func isRemoteBranch(remote vdmspec.Remote) bool {
+	_, err := runGit(remote.LocalPath, "rev-parse", "--verify", "--quiet", "refs/remotes/origin/"+remote.Version)
+	return err == nil
+}
+
+// runGit runs a git command against the repository at the provided path, and
+// returns its trimmed output.
+func runGit(repoPath string, args ...string) (string, error) {
+	gitArgs := append([]string{"-C", repoPath}, args...)
+	gitCmd := exec.Command("git", gitArgs...)
+	output, err := gitCmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("running 'git %s': exec error '%w', with output: %s", strings.Join(args, " "), err, string(output))
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
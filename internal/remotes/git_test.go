@@ -2,6 +2,9 @@ package remotes
 
 import (
 	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/opensourcecorp/vdm/internal/vdmspec"
@@ -21,7 +24,7 @@ func getTestGitSpec() vdmspec.Remote {
 
 func TestSyncGit(t *testing.T) {
 	spec := getTestGitSpec()
-	err := SyncGit(spec)
+	_, err := SyncGit(spec, vdmspec.LockedRemote{}, nil)
 	require.NoError(t, err)
 
 	defer t.Cleanup(func() {
@@ -40,13 +43,13 @@ func TestCheckGitAvailable(t *testing.T) {
 	t.Run("checkGitAvailable", func(t *testing.T) {
 		t.Run("no error when git is available", func(t *testing.T) {
 			// Host of this test better have git available lol
-			gitAvailable := checkGitAvailable()
+			gitAvailable := checkGitAvailable(nil)
 			require.NoError(t, gitAvailable)
 		})
 
 		t.Run("error when git is NOT available", func(t *testing.T) {
 			t.Setenv("PATH", "")
-			gitAvailable := checkGitAvailable()
+			gitAvailable := checkGitAvailable(nil)
 			assert.Error(t, gitAvailable)
 		})
 	})
@@ -54,7 +57,7 @@ func TestCheckGitAvailable(t *testing.T) {
 
 func TestGitClone(t *testing.T) {
 	spec := getTestGitSpec()
-	cloneErr := gitClone(spec)
+	cloneErr := gitClone(spec, false, nil)
 
 	defer t.Cleanup(func() {
 		if cleanupErr := os.RemoveAll(spec.LocalPath); cleanupErr != nil {
@@ -78,3 +81,299 @@ func TestGitClone(t *testing.T) {
 		assert.False(t, sampleFile.IsDir())
 	})
 }
+
+// newTestGitRepo creates a local git repository to use as a remote, with a
+// 'contents.txt' file whose contents change with each of its tags. It has the
+// tags 'v0.1.0' and 'v0.2.0', and a branch 'dev' one commit ahead of those.
+func newTestGitRepo(t *testing.T) string {
+	t.Helper()
+
+	repoPath := t.TempDir()
+	runTestGit(t, repoPath, "init", "--initial-branch=main")
+	for _, version := range []string{"v0.1.0", "v0.2.0"} {
+		require.NoError(t, os.WriteFile(filepath.Join(repoPath, "contents.txt"), []byte(version), 0644))
+		runTestGit(t, repoPath, "add", "contents.txt")
+		runTestGit(t, repoPath, "commit", "-m", version)
+		runTestGit(t, repoPath, "tag", version)
+	}
+	runTestGit(t, repoPath, "checkout", "-b", "dev")
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "contents.txt"), []byte("dev"), 0644))
+	runTestGit(t, repoPath, "commit", "-am", "dev")
+	runTestGit(t, repoPath, "checkout", "main")
+
+	return repoPath
+}
+
+// runTestGit runs a git command in the provided repository, failing the test
+// if it errors.
+func runTestGit(t *testing.T, repoPath string, args ...string) string {
+	t.Helper()
+
+	args = append([]string{"-C", repoPath, "-c", "user.name=vdm", "-c", "user.email=vdm@example.com"}, args...)
+	output, err := exec.Command("git", args...).CombinedOutput()
+	require.NoError(t, err, string(output))
+
+	return strings.TrimSpace(string(output))
+}
+
+func TestSyncGitKeepGitDir(t *testing.T) {
+	remote := vdmspec.Remote{
+		Type:       vdmspec.GitType,
+		Remote:     newTestGitRepo(t),
+		Version:    "v0.1.0",
+		LocalPath:  filepath.Join(t.TempDir(), "kept"),
+		KeepGitDir: true,
+	}
+	_, err := SyncGit(remote, vdmspec.LockedRemote{}, nil)
+	require.NoError(t, err)
+
+	t.Run(".git directory was kept", func(t *testing.T) {
+		_, err := os.Stat(filepath.Join(remote.LocalPath, ".git"))
+		assert.NoError(t, err)
+		assert.True(t, HasKeptGitDir(remote))
+	})
+
+	// Local work that should survive later syncs, since those shouldn't re-clone
+	localFile := filepath.Join(remote.LocalPath, "local.txt")
+	require.NoError(t, os.WriteFile(localFile, []byte("mine"), 0644))
+
+	for _, version := range []string{"v0.2.0", "dev", "latest"} {
+		t.Run("updates in place to "+version, func(t *testing.T) {
+			remote.Version = version
+			_, err := SyncGit(remote, vdmspec.LockedRemote{}, nil)
+			require.NoError(t, err)
+
+			want := version
+			if version == "latest" {
+				want = "v0.2.0" // the remote's HEAD
+			}
+			got, err := os.ReadFile(filepath.Join(remote.LocalPath, "contents.txt"))
+			require.NoError(t, err)
+			assert.Equal(t, want, string(got))
+
+			_, err = os.Stat(localFile)
+			assert.NoError(t, err)
+		})
+	}
+
+	t.Run("HasKeptGitDir is false when not keeping it", func(t *testing.T) {
+		remote.KeepGitDir = false
+		assert.False(t, HasKeptGitDir(remote))
+	})
+}
+
+func TestSyncGitLocked(t *testing.T) {
+	repoPath := newTestGitRepo(t)
+	lockedCommit := runTestGit(t, repoPath, "rev-parse", "v0.1.0")
+
+	remote := vdmspec.Remote{
+		Type:      vdmspec.GitType,
+		Remote:    repoPath,
+		Version:   "latest",
+		LocalPath: filepath.Join(t.TempDir(), "locked"),
+	}
+
+	t.Run("resolves the commit that was checked out", func(t *testing.T) {
+		resolved, err := SyncGit(remote, vdmspec.LockedRemote{}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, runTestGit(t, repoPath, "rev-parse", "HEAD"), resolved.Commit)
+		assert.Equal(t, remote, resolved.Remote)
+		assert.False(t, resolved.FetchedAt.IsZero())
+		require.NoError(t, os.RemoveAll(remote.LocalPath))
+	})
+
+	t.Run("checks out the locked commit instead of the version", func(t *testing.T) {
+		resolved, err := SyncGit(remote, vdmspec.LockedRemote{Remote: remote, Commit: lockedCommit}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, lockedCommit, resolved.Commit)
+
+		got, err := os.ReadFile(filepath.Join(remote.LocalPath, "contents.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "v0.1.0", string(got))
+	})
+}
+
+func TestResolveGitVersion(t *testing.T) {
+	repoPath := newTestGitRepo(t)
+	remote := vdmspec.Remote{Type: vdmspec.GitType, Remote: repoPath}
+
+	for version, ref := range map[string]string{
+		"latest": "main",
+		"v0.1.0": "v0.1.0",
+		"dev":    "dev",
+	} {
+		t.Run(version, func(t *testing.T) {
+			remote.Version = version
+			got, err := ResolveGitVersion(remote)
+			require.NoError(t, err)
+			assert.Equal(t, runTestGit(t, repoPath, "rev-parse", ref), got)
+		})
+	}
+
+	t.Run("commit hashes don't resolve", func(t *testing.T) {
+		remote.Version = runTestGit(t, repoPath, "rev-parse", "v0.1.0")
+		got, err := ResolveGitVersion(remote)
+		require.NoError(t, err)
+		assert.Empty(t, got)
+	})
+}
+
+func TestSyncGitVersionConstraint(t *testing.T) {
+	repoPath := newTestGitRepo(t)
+	runTestGit(t, repoPath, "tag", "v0.3.0-rc.1", "dev")
+
+	remote := vdmspec.Remote{
+		Type:      vdmspec.GitType,
+		Remote:    repoPath,
+		Version:   "^0.1",
+		LocalPath: filepath.Join(t.TempDir(), "constrained"),
+	}
+
+	t.Run("resolves to the newest matching tag", func(t *testing.T) {
+		resolved, err := SyncGit(remote, vdmspec.LockedRemote{}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "v0.1.0", resolved.Tag)
+		assert.Equal(t, runTestGit(t, repoPath, "rev-parse", "v0.1.0"), resolved.Commit)
+		assert.Equal(t, remote, resolved.Remote)
+		require.NoError(t, os.RemoveAll(remote.LocalPath))
+	})
+
+	t.Run("honors the locked commit & tag", func(t *testing.T) {
+		wider := remote
+		wider.Version = ">=0.1.0"
+		locked := vdmspec.LockedRemote{Remote: wider, Commit: runTestGit(t, repoPath, "rev-parse", "v0.1.0"), Tag: "v0.1.0"}
+		resolved, err := SyncGit(wider, locked, nil)
+		require.NoError(t, err)
+		assert.Equal(t, "v0.1.0", resolved.Tag)
+
+		got, err := os.ReadFile(filepath.Join(wider.LocalPath, "contents.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "v0.1.0", string(got))
+		require.NoError(t, os.RemoveAll(wider.LocalPath))
+	})
+
+	t.Run("only considers prereleases if allowed", func(t *testing.T) {
+		prerelease := remote
+		prerelease.Version = ">=0.2.0"
+		for _, tc := range []struct {
+			allowed bool
+			want    string
+		}{
+			{allowed: false, want: "v0.2.0"},
+			{allowed: true, want: "v0.3.0-rc.1"},
+		} {
+			prerelease.Prerelease = tc.allowed
+			got, err := ResolveGitConstraint(prerelease)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		}
+
+		commit, err := ResolveGitVersion(prerelease)
+		require.NoError(t, err)
+		assert.Equal(t, runTestGit(t, repoPath, "rev-parse", "dev"), commit)
+	})
+
+	t.Run("fails if nothing matches", func(t *testing.T) {
+		unsatisfiable := remote
+		unsatisfiable.Version = "^1"
+		_, err := SyncGit(unsatisfiable, vdmspec.LockedRemote{}, nil)
+		assert.Error(t, err)
+		assert.NoDirExists(t, unsatisfiable.LocalPath)
+	})
+}
+
+func TestSyncGitSubdir(t *testing.T) {
+	repoPath := newTestGitRepo(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(repoPath, "proto", "nested"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "proto", "nested", "some.proto"), []byte("syntax"), 0644))
+	runTestGit(t, repoPath, "add", "proto")
+	runTestGit(t, repoPath, "commit", "-m", "proto")
+	runTestGit(t, repoPath, "tag", "v0.3.0")
+	head := runTestGit(t, repoPath, "rev-parse", "HEAD")
+
+	for _, version := range []string{"v0.3.0", "latest"} {
+		t.Run("places only the subdir's contents at version "+version, func(t *testing.T) {
+			remote := vdmspec.Remote{
+				Type:      vdmspec.GitType,
+				Remote:    repoPath,
+				Version:   version,
+				LocalPath: filepath.Join(t.TempDir(), "protos"),
+				Subdir:    "proto",
+			}
+			resolved, err := SyncGit(remote, vdmspec.LockedRemote{}, nil)
+			require.NoError(t, err)
+			assert.Equal(t, head, resolved.Commit)
+			assert.Equal(t, remote, resolved.Remote)
+
+			got, err := os.ReadFile(filepath.Join(remote.LocalPath, "nested", "some.proto"))
+			require.NoError(t, err)
+			assert.Equal(t, "syntax", string(got))
+
+			assert.NoFileExists(t, filepath.Join(remote.LocalPath, "contents.txt"))
+			assert.NoDirExists(t, filepath.Join(remote.LocalPath, ".git"))
+			assert.NoDirExists(t, remote.LocalPath+".vdm-clone")
+		})
+	}
+
+	t.Run("fails if the subdir doesn't exist at the version", func(t *testing.T) {
+		remote := vdmspec.Remote{
+			Type:      vdmspec.GitType,
+			Remote:    repoPath,
+			Version:   "v0.1.0",
+			LocalPath: filepath.Join(t.TempDir(), "protos"),
+			Subdir:    "proto",
+		}
+		_, err := SyncGit(remote, vdmspec.LockedRemote{}, nil)
+		assert.Error(t, err)
+		assert.NoDirExists(t, remote.LocalPath)
+		assert.NoDirExists(t, remote.LocalPath+".vdm-clone")
+	})
+
+	t.Run("fails if the subdir is a symlink", func(t *testing.T) {
+		outside := t.TempDir()
+		require.NoError(t, os.Symlink(outside, filepath.Join(repoPath, "escape")))
+		runTestGit(t, repoPath, "add", "escape")
+		runTestGit(t, repoPath, "commit", "-m", "escape")
+
+		remote := vdmspec.Remote{
+			Type:      vdmspec.GitType,
+			Remote:    repoPath,
+			Version:   "latest",
+			LocalPath: filepath.Join(t.TempDir(), "escape"),
+			Subdir:    "escape",
+		}
+		_, err := SyncGit(remote, vdmspec.LockedRemote{}, nil)
+		assert.Error(t, err)
+		_, err = os.Lstat(remote.LocalPath)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+		assert.NoDirExists(t, remote.LocalPath+".vdm-clone")
+	})
+}
+
+func TestSyncGitFilters(t *testing.T) {
+	repoPath := newTestGitRepo(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(repoPath, "docs"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "docs", "index.md"), []byte("docs"), 0644))
+	runTestGit(t, repoPath, "add", "docs")
+	runTestGit(t, repoPath, "commit", "-m", "docs")
+
+	remote := vdmspec.Remote{
+		Type:      vdmspec.GitType,
+		Remote:    repoPath,
+		Version:   "latest",
+		LocalPath: filepath.Join(t.TempDir(), "filtered"),
+		Exclude:   []string{"docs"},
+	}
+	_, err := SyncGit(remote, vdmspec.LockedRemote{}, nil)
+	require.NoError(t, err)
+
+	assert.FileExists(t, filepath.Join(remote.LocalPath, "contents.txt"))
+	assert.NoDirExists(t, filepath.Join(remote.LocalPath, "docs"))
+
+	require.NoError(t, remote.WriteVDMMeta())
+	vdmMeta, err := remote.GetVDMMeta()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"docs"}, vdmMeta.Exclude)
+	assert.Len(t, vdmMeta.Manifest, 1)
+	assert.Contains(t, vdmMeta.Manifest, "contents.txt")
+}
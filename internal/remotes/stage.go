@@ -0,0 +1,211 @@
+package remotes
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/opensourcecorp/vdm/internal/message"
+	"github.com/opensourcecorp/vdm/internal/vdmspec"
+)
+
+// Staged tracks a remote whose new content has been retrieved into a staging
+// directory, so that it can be swapped into its local path all at once (and
+// swapped back out again if something else goes wrong). The staging directory
+// is created next to the local path, so that the swap is a simple rename on the
+// same filesystem.
+type Staged struct {
+	// Remote is the remote as it's specified, with its real local path.
+	Remote vdmspec.Remote
+
+	dir        string
+	hasContent bool
+	backups    map[string]string // original path -> backup path
+	installed  []string          // paths that were moved into place
+}
+
+// Stage creates a staging directory for the remote. If the remote is a clone
+// whose .git directory is kept, its existing content is copied into the staging
+// directory first, so that it can be updated there instead of re-cloned.
+func Stage(remote vdmspec.Remote, out *message.Group) (*Staged, error) {
+	parentDir := filepath.Dir(remote.LocalPath)
+	if err := os.MkdirAll(parentDir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("making parent directories for '%s': %w", remote.LocalPath, err)
+	}
+
+	dir, err := os.MkdirTemp(parentDir, ".vdm-staging-")
+	if err != nil {
+		return nil, fmt.Errorf("making staging directory for '%s': %w", remote.LocalPath, err)
+	}
+	out.Debugf("%s: staging in '%s'", remote.OpMsg(), dir)
+
+	staged := &Staged{
+		Remote:     remote,
+		dir:        dir,
+		hasContent: true,
+		backups:    make(map[string]string),
+	}
+
+	if HasKeptGitDir(remote) {
+		out.Debugf("%s: copying existing clone into staging directory to update it there", remote.OpMsg())
+		if err := copyDir(remote.LocalPath, staged.StagedRemote().LocalPath); err != nil {
+			return nil, errors.Join(
+				fmt.Errorf("copying existing clone into staging directory: %w", err),
+				staged.Cleanup(),
+			)
+		}
+	}
+
+	return staged, nil
+}
+
+// StageRemoval prepares to remove the remote's content from disk, in a way that
+// can still be rolled back until [Staged.Cleanup] is called.
+func StageRemoval(remote vdmspec.Remote, out *message.Group) (*Staged, error) {
+	staged, err := Stage(remote, out)
+	if err != nil {
+		return nil, err
+	}
+	staged.hasContent = false
+
+	return staged, nil
+}
+
+// StagedRemote returns a copy of the remote whose local path points into the
+// staging directory, which is what should actually be synced.
+func (s *Staged) StagedRemote() vdmspec.Remote {
+	stagedRemote := s.Remote
+	stagedRemote.LocalPath = filepath.Join(s.dir, filepath.Base(s.Remote.LocalPath))
+	return stagedRemote
+}
+
+// Install moves any existing content for the remote (and for the remote it's
+// replacing, if provided) out of the way into the staging directory, and then
+// moves the staged content into the remote's local path.
+func (s *Staged) Install(previous *vdmspec.Remote, out *message.Group) error {
+	toBackUp := []string{s.Remote.LocalPath, s.Remote.MakeMetaFilePath()}
+	if previous != nil {
+		toBackUp = append(toBackUp, previous.LocalPath, previous.MakeMetaFilePath())
+	}
+
+	for _, path := range toBackUp {
+		if _, ok := s.backups[path]; ok {
+			continue
+		}
+		if _, err := os.Lstat(path); errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("checking for existing content at '%s': %w", path, err)
+		}
+
+		backupPath := filepath.Join(s.dir, fmt.Sprintf("backup-%d", len(s.backups)))
+		out.Debugf("%s: backing up '%s' to '%s'", s.Remote.OpMsg(), path, backupPath)
+		if err := os.Rename(path, backupPath); err != nil {
+			return fmt.Errorf("backing up existing content at '%s': %w", path, err)
+		}
+		s.backups[path] = backupPath
+	}
+
+	if !s.hasContent {
+		return nil
+	}
+
+	out.Debugf("%s: moving staged content into place", s.Remote.OpMsg())
+	if err := os.Rename(s.StagedRemote().LocalPath, s.Remote.LocalPath); err != nil {
+		return fmt.Errorf("moving staged content into place at '%s': %w", s.Remote.LocalPath, err)
+	}
+	s.installed = append(s.installed, s.Remote.LocalPath, s.Remote.MakeMetaFilePath())
+
+	return nil
+}
+
+// Rollback undoes [Staged.Install], putting back whatever was at the remote's
+// local path before.
+func (s *Staged) Rollback(out *message.Group) error {
+	var allErrors []error
+
+	out.Debugf("%s: rolling back", s.Remote.OpMsg())
+	for _, path := range s.installed {
+		if err := os.RemoveAll(path); err != nil {
+			allErrors = append(allErrors, fmt.Errorf("removing new content at '%s': %w", path, err))
+		}
+	}
+	s.installed = nil
+
+	for path, backupPath := range s.backups {
+		if err := os.Rename(backupPath, path); err != nil {
+			allErrors = append(allErrors, fmt.Errorf("restoring previous content at '%s': %w", path, err))
+			continue
+		}
+		delete(s.backups, path)
+	}
+
+	return errors.Join(allErrors...)
+}
+
+// Cleanup removes the staging directory, along with any backups in it. After
+// this, the install can no longer be rolled back.
+func (s *Staged) Cleanup() error {
+	if err := os.RemoveAll(s.dir); err != nil {
+		return fmt.Errorf("removing staging directory '%s': %w", s.dir, err)
+	}
+	return nil
+}
+
+// copyDir recursively copies the directory at src to dst, keeping file modes &
+// symlinks as they are.
+func copyDir(src string, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, relPath)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case d.Type()&fs.ModeSymlink != 0:
+			linkTarget, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(linkTarget, target)
+		default:
+			return copyFile(path, target, info.Mode().Perm())
+		}
+	})
+}
+
+func copyFile(src string, dst string, perm fs.FileMode) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, in.Close())
+	}()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, out.Close())
+	}()
+
+	_, err = io.Copy(out, in)
+	return err
+}
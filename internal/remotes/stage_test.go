@@ -0,0 +1,95 @@
+package remotes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opensourcecorp/vdm/internal/vdmspec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStage(t *testing.T) {
+	newRemote := func(t *testing.T) vdmspec.Remote {
+		return vdmspec.Remote{
+			Type:      vdmspec.FileType,
+			Remote:    "https://some-remote/some.file",
+			LocalPath: filepath.Join(t.TempDir(), "deps", "some.file"),
+		}
+	}
+
+	readFile := func(t *testing.T, path string) string {
+		got, err := os.ReadFile(path)
+		require.NoError(t, err)
+		return string(got)
+	}
+
+	t.Run("Install swaps staged content into place", func(t *testing.T) {
+		remote := newRemote(t)
+		require.NoError(t, os.MkdirAll(filepath.Dir(remote.LocalPath), 0755))
+		require.NoError(t, os.WriteFile(remote.LocalPath, []byte("old"), 0644))
+
+		staged, err := Stage(remote, nil)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(staged.StagedRemote().LocalPath, []byte("new"), 0644))
+		assert.Equal(t, "old", readFile(t, remote.LocalPath))
+
+		require.NoError(t, staged.Install(nil, nil))
+		assert.Equal(t, "new", readFile(t, remote.LocalPath))
+
+		t.Run("and Rollback swaps it back", func(t *testing.T) {
+			require.NoError(t, staged.Rollback(nil))
+			assert.Equal(t, "old", readFile(t, remote.LocalPath))
+		})
+
+		t.Run("and Cleanup leaves nothing behind", func(t *testing.T) {
+			require.NoError(t, staged.Cleanup())
+			entries, err := os.ReadDir(filepath.Dir(remote.LocalPath))
+			require.NoError(t, err)
+			require.Len(t, entries, 1)
+			assert.Equal(t, "some.file", entries[0].Name())
+		})
+	})
+
+	t.Run("StageRemoval moves content out of place", func(t *testing.T) {
+		remote := newRemote(t)
+		require.NoError(t, os.MkdirAll(filepath.Dir(remote.LocalPath), 0755))
+		require.NoError(t, os.WriteFile(remote.LocalPath, []byte("old"), 0644))
+		require.NoError(t, remote.WriteVDMMeta())
+
+		staged, err := StageRemoval(remote, nil)
+		require.NoError(t, err)
+		require.NoError(t, staged.Install(nil, nil))
+
+		_, err = os.Stat(remote.LocalPath)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+		_, err = os.Stat(remote.MakeMetaFilePath())
+		assert.ErrorIs(t, err, os.ErrNotExist)
+
+		require.NoError(t, staged.Rollback(nil))
+		assert.Equal(t, "old", readFile(t, remote.LocalPath))
+		_, err = os.Stat(remote.MakeMetaFilePath())
+		assert.NoError(t, err)
+		require.NoError(t, staged.Cleanup())
+	})
+
+	t.Run("kept clones are copied into staging", func(t *testing.T) {
+		remote := vdmspec.Remote{
+			Type:       vdmspec.GitType,
+			Remote:     newTestGitRepo(t),
+			Version:    "v0.1.0",
+			LocalPath:  filepath.Join(t.TempDir(), "kept"),
+			KeepGitDir: true,
+		}
+		_, err := SyncGit(remote, vdmspec.LockedRemote{}, nil)
+		require.NoError(t, err)
+
+		staged, err := Stage(remote, nil)
+		require.NoError(t, err)
+		defer func() { require.NoError(t, staged.Cleanup()) }()
+
+		assert.True(t, HasKeptGitDir(staged.StagedRemote()))
+		assert.Equal(t, "v0.1.0", readFile(t, filepath.Join(staged.StagedRemote().LocalPath, "contents.txt")))
+	})
+}
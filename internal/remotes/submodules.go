@@ -0,0 +1,230 @@
+package remotes
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/opensourcecorp/vdm/internal/vdmspec"
+)
+
+// GitModulesFileName is the name of the file that git declares a repository's
+// submodules in.
+const GitModulesFileName string = ".gitmodules"
+
+// GitSubmodule is a submodule declared in a repository's .gitmodules file.
+type GitSubmodule struct {
+	Name   string
+	Path   string
+	URL    string
+	Branch string
+	// Commit is the commit that the submodule is pinned to by the gitlink in the
+	// repository's index. It's empty if the submodule isn't in the index.
+	Commit string
+}
+
+// Remote returns a git remote that retrieves the same thing as the submodule,
+// at the same path. It's pinned to the submodule's commit if there is one, and
+// otherwise to the branch it tracks (or 'latest').
+func (s GitSubmodule) Remote() vdmspec.Remote {
+	version := s.Commit
+	if version == "" {
+		version = s.Branch
+	}
+	if version == "" {
+		version = "latest"
+	}
+
+	return vdmspec.Remote{
+		Remote:    s.URL,
+		Version:   version,
+		LocalPath: "./" + path.Clean(s.Path),
+	}
+}
+
+// ListGitSubmodules reads the submodules declared in the .gitmodules file at
+// the root of the repository at repoPath, along with the commits that they're
+// pinned to in its index. Relative submodule URLs are resolved against the
+// repository's 'origin' remote, the same way git does.
+func ListGitSubmodules(repoPath string) ([]GitSubmodule, error) {
+	if err := checkGitAvailable(nil); err != nil {
+		return nil, fmt.Errorf("reading submodules needs git, but it may not be installed/available on PATH: %w", err)
+	}
+
+	gitModulesPath := filepath.Join(repoPath, GitModulesFileName)
+	if _, err := os.Stat(gitModulesPath); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", GitModulesFileName, err)
+	}
+
+	// With --null, each entry is the key, a newline, and then the value
+	config, err := runGit(repoPath, "config", "--file", GitModulesFileName, "--null", "--get-regexp", `^submodule\.`)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", GitModulesFileName, err)
+	}
+
+	var submodules []GitSubmodule
+	indexes := make(map[string]int)
+	for _, entry := range strings.Split(config, "\x00") {
+		key, value, _ := strings.Cut(entry, "\n")
+		key = strings.TrimPrefix(key, "submodule.")
+		lastDot := strings.LastIndex(key, ".")
+		if key == "" || lastDot < 0 {
+			continue
+		}
+		name, variable := key[:lastDot], key[lastDot+1:]
+
+		i, ok := indexes[name]
+		if !ok {
+			i = len(submodules)
+			indexes[name] = i
+			submodules = append(submodules, GitSubmodule{Name: name})
+		}
+		switch variable {
+		case "path":
+			submodules[i].Path = value
+		case "url":
+			submodules[i].URL = value
+		case "branch":
+			submodules[i].Branch = value
+		}
+	}
+
+	commits, err := listGitlinks(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var allErrors []error
+	for i, submodule := range submodules {
+		if submodule.Path == "" || submodule.URL == "" {
+			allErrors = append(allErrors, fmt.Errorf("submodule '%s' is missing its path or url in %s", submodule.Name, GitModulesFileName))
+			continue
+		}
+		submodules[i].Commit = commits[path.Clean(submodule.Path)]
+
+		if strings.HasPrefix(submodule.URL, "./") || strings.HasPrefix(submodule.URL, "../") {
+			resolved, err := resolveRelativeSubmoduleURL(repoPath, submodule.URL)
+			if err != nil {
+				allErrors = append(allErrors, fmt.Errorf("submodule '%s': %w", submodule.Name, err))
+				continue
+			}
+			submodules[i].URL = resolved
+		}
+	}
+	if len(allErrors) > 0 {
+		return nil, errors.Join(allErrors...)
+	}
+
+	return submodules, nil
+}
+
+// listGitlinks returns the commit of every gitlink (i.e. submodule) in the
+// index of the repository at repoPath, keyed by its path.
+func listGitlinks(repoPath string) (map[string]string, error) {
+	// Each line looks like '<mode> <object> <stage>\t<path>'
+	output, err := runGit(repoPath, "ls-files", "--stage")
+	if err != nil {
+		return nil, fmt.Errorf("listing gitlinks in index: %w", err)
+	}
+
+	commits := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		info, filePath, ok := strings.Cut(line, "\t")
+		fields := strings.Fields(info)
+		if !ok || len(fields) != 3 || fields[0] != "160000" {
+			continue
+		}
+		commits[path.Clean(filePath)] = fields[1]
+	}
+
+	return commits, nil
+}
+
+// resolveRelativeSubmoduleURL resolves a submodule URL like '../other.git'
+// against the URL of the repository's 'origin' remote.
+func resolveRelativeSubmoduleURL(repoPath string, relativeURL string) (string, error) {
+	base, err := runGit(repoPath, "remote", "get-url", "origin")
+	if err != nil {
+		return "", fmt.Errorf("its url '%s' is relative, but the repository's origin url couldn't be found to resolve it against: %w", relativeURL, err)
+	}
+	base = strings.TrimSuffix(base, "/")
+
+	for {
+		switch {
+		case strings.HasPrefix(relativeURL, "./"):
+			relativeURL = strings.TrimPrefix(relativeURL, "./")
+		case strings.HasPrefix(relativeURL, "../"):
+			relativeURL = strings.TrimPrefix(relativeURL, "../")
+			// Covers both 'https://host/org/repo' and 'git@host:org/repo'
+			base = strings.TrimRight(base, "/")
+			i := strings.LastIndexAny(base, "/:")
+			if i < 0 {
+				return "", fmt.Errorf("its url is relative, but goes above the origin url '%s'", base)
+			}
+			base = base[:i+1]
+		default:
+			if strings.HasSuffix(base, "/") || strings.HasSuffix(base, ":") {
+				return base + relativeURL, nil
+			}
+			return base + "/" + relativeURL, nil
+		}
+	}
+}
+
+// RemoveGitSubmodule deinitializes the submodule in the repository at repoPath,
+// and removes it from the index & .gitmodules, leaving nothing at its path.
+// Like 'git submodule deinit', it refuses to if the submodule's working tree
+// has local modifications. The submodule's git directory under '.git/modules'
+// is left alone, so that no unpushed commits in it are lost.
+func RemoveGitSubmodule(repoPath string, submodule GitSubmodule) error {
+	if _, err := runGit(repoPath, "submodule", "deinit", "--", submodule.Path); err != nil {
+		return fmt.Errorf("deinitializing submodule '%s': %w", submodule.Name, err)
+	}
+	if _, err := runGit(repoPath, "rm", "--quiet", "--", submodule.Path); err != nil {
+		return fmt.Errorf("removing submodule '%s': %w", submodule.Name, err)
+	}
+
+	return nil
+}
+
+// RestoreGitSubmodules undoes [RemoveGitSubmodule] for the submodules in the
+// repository at repoPath, given what its .gitmodules file had in it before they
+// were removed. They're put back in the index at the commits they were pinned
+// to, and checked back out from their git directories under '.git/modules', so
+// nothing needs to be fetched.
+func RestoreGitSubmodules(repoPath string, gitModules []byte, submodules []GitSubmodule) error {
+	if len(submodules) == 0 {
+		return nil
+	}
+
+	if err := os.WriteFile(filepath.Join(repoPath, GitModulesFileName), gitModules, 0644); err != nil {
+		return fmt.Errorf("restoring %s: %w", GitModulesFileName, err)
+	}
+	if _, err := runGit(repoPath, "add", "--", GitModulesFileName); err != nil {
+		return fmt.Errorf("restoring %s: %w", GitModulesFileName, err)
+	}
+
+	paths := make([]string, 0, len(submodules))
+	for _, submodule := range submodules {
+		if submodule.Commit == "" {
+			// It wasn't in the index to begin with
+			continue
+		}
+		if _, err := runGit(repoPath, "update-index", "--add", "--cacheinfo", "160000,"+submodule.Commit+","+submodule.Path); err != nil {
+			return fmt.Errorf("restoring submodule '%s' to the index: %w", submodule.Name, err)
+		}
+		paths = append(paths, submodule.Path)
+	}
+	if len(paths) == 0 {
+		return nil
+	}
+
+	if _, err := runGit(repoPath, append([]string{"submodule", "update", "--init", "--"}, paths...)...); err != nil {
+		return fmt.Errorf("checking submodules back out: %w", err)
+	}
+
+	return nil
+}
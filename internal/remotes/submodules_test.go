@@ -0,0 +1,88 @@
+package remotes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opensourcecorp/vdm/internal/vdmspec"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestSuperproject returns a repository with a submodule of a
+// [newTestGitRepo] at 'libs/sub', pinned to its 'v0.1.0' tag, and the path to
+// the submodule's source.
+func newTestSuperproject(t *testing.T) (string, string) {
+	t.Helper()
+
+	subPath := newTestGitRepo(t)
+	repoPath := t.TempDir()
+	runTestGit(t, repoPath, "init", "--initial-branch=main")
+	runTestGit(t, repoPath, "-c", "protocol.file.allow=always", "submodule", "add", "--branch", "main", subPath, "libs/sub")
+	runTestGit(t, filepath.Join(repoPath, "libs", "sub"), "checkout", "v0.1.0")
+	runTestGit(t, repoPath, "add", "libs/sub")
+	runTestGit(t, repoPath, "commit", "-m", "add submodule")
+
+	return repoPath, subPath
+}
+
+func TestListGitSubmodules(t *testing.T) {
+	repoPath, subPath := newTestSuperproject(t)
+	subCommit := runTestGit(t, subPath, "rev-parse", "v0.1.0")
+
+	submodules, err := ListGitSubmodules(repoPath)
+	require.NoError(t, err)
+	assert.Equal(t, []GitSubmodule{{
+		Name:   "libs/sub",
+		Path:   "libs/sub",
+		URL:    subPath,
+		Branch: "main",
+		Commit: subCommit,
+	}}, submodules)
+
+	assert.Equal(
+		t,
+		vdmspec.Remote{Remote: subPath, Version: subCommit, LocalPath: "./libs/sub"},
+		submodules[0].Remote(),
+	)
+
+	t.Run("relative urls are resolved against origin", func(t *testing.T) {
+		runTestGit(t, repoPath, "remote", "add", "origin", "https://example.com/org/super.git")
+		runTestGit(t, repoPath, "config", "--file", GitModulesFileName, "submodule.libs/sub.url", "../other.git")
+
+		submodules, err := ListGitSubmodules(repoPath)
+		require.NoError(t, err)
+		require.Len(t, submodules, 1)
+		assert.Equal(t, "https://example.com/org/other.git", submodules[0].URL)
+	})
+
+	t.Run("missing .gitmodules is an error", func(t *testing.T) {
+		_, err := ListGitSubmodules(t.TempDir())
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+func TestResolveRelativeSubmoduleURL(t *testing.T) {
+	for origin, tcs := range map[string]map[string]string{
+		"https://example.com/org/super.git": {
+			"../other.git":   "https://example.com/org/other.git",
+			"../../org2/x":   "https://example.com/org2/x",
+			"./nested/x.git": "https://example.com/org/super.git/nested/x.git",
+		},
+		"git@example.com:org/super.git": {
+			"../other.git":    "git@example.com:org/other.git",
+			"../../other.git": "git@example.com:other.git",
+		},
+	} {
+		repoPath := t.TempDir()
+		runTestGit(t, repoPath, "init")
+		runTestGit(t, repoPath, "remote", "add", "origin", origin)
+
+		for relativeURL, want := range tcs {
+			got, err := resolveRelativeSubmoduleURL(repoPath, relativeURL)
+			require.NoError(t, err, relativeURL)
+			assert.Equal(t, want, got, relativeURL)
+		}
+	}
+}
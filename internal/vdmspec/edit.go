@@ -0,0 +1,574 @@
+package vdmspec
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/opensourcecorp/vdm/internal/message"
+	"gopkg.in/yaml.v3"
+)
+
+// SpecFile is the raw contents of a specfile, which can be edited in place.
+// Edits only ever replace the exact bytes of the values they change, so
+// comments, key order, quoting, and other formatting are all left as they were.
+// Since JSON is also YAML, this works for JSON specfiles too.
+type SpecFile struct {
+	Path     string
+	contents []byte
+	root     yaml.Node
+}
+
+// ReadSpecFile reads the specfile at the provided path for editing.
+func ReadSpecFile(specFilePath string) (*SpecFile, error) {
+	contents, err := os.ReadFile(specFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("reading spec file '%s': %w", specFilePath, err)
+	}
+
+	f := &SpecFile{Path: specFilePath}
+	if err := f.setContents(contents); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// setContents replaces the specfile's contents, and re-parses them so that the
+// positions of every node are up to date for the next edit.
+func (f *SpecFile) setContents(contents []byte) error {
+	var root yaml.Node
+	if err := yaml.Unmarshal(contents, &root); err != nil {
+		return fmt.Errorf("parsing spec file '%s': %w", f.Path, err)
+	}
+
+	f.contents, f.root = contents, root
+	return nil
+}
+
+// Bytes returns the specfile's current contents.
+func (f *SpecFile) Bytes() []byte {
+	return f.contents
+}
+
+// Spec returns the spec that the specfile's current contents describe.
+func (f *SpecFile) Spec() (Spec, error) {
+	return parseSpec(f.Path, f.contents)
+}
+
+// Write writes the specfile's current contents back to disk.
+func (f *SpecFile) Write() error {
+	message.Debugf("writing spec file to '%s'", f.Path)
+	if err := os.WriteFile(f.Path, f.contents, 0644); err != nil {
+		return fmt.Errorf("writing spec file '%s': %w", f.Path, err)
+	}
+	return nil
+}
+
+// SetVersion sets the version of the remote at the provided index in the
+// specfile. If the remote doesn't have a version yet, one is added to it, which
+// is only possible for remotes written in YAML's block style.
+func (f *SpecFile) SetVersion(remoteIndex int, version string) error {
+	remoteNode, err := f.remoteNode(remoteIndex)
+	if err != nil {
+		return err
+	}
+
+	if versionNode := mappingValue(remoteNode, "version"); versionNode != nil {
+		return f.replaceScalar(versionNode, version)
+	}
+
+	return f.addScalar(remoteNode, "version", version)
+}
+
+// remotesNode returns the sequence node for the specfile's list of remotes.
+func (f *SpecFile) remotesNode() (*yaml.Node, error) {
+	if len(f.root.Content) == 0 {
+		return nil, fmt.Errorf("spec file '%s' is empty", f.Path)
+	}
+
+	remotesNode := mappingValue(f.root.Content[0], "remotes")
+	if remotesNode == nil || remotesNode.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("spec file '%s' doesn't have a list of remotes", f.Path)
+	}
+
+	return remotesNode, nil
+}
+
+// remoteNode returns the mapping node for the remote at the provided index.
+func (f *SpecFile) remoteNode(remoteIndex int) (*yaml.Node, error) {
+	remotesNode, err := f.remotesNode()
+	if err != nil {
+		return nil, err
+	}
+	if remoteIndex < 0 || remoteIndex >= len(remotesNode.Content) {
+		return nil, fmt.Errorf("spec file '%s' has no remote #%d", f.Path, remoteIndex)
+	}
+
+	remoteNode := remotesNode.Content[remoteIndex]
+	if remoteNode.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("remote #%d in spec file '%s' isn't a mapping", remoteIndex, f.Path)
+	}
+
+	return remoteNode, nil
+}
+
+// mappingValue returns the value for the key in the mapping node, or nil if
+// it's not there.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// lineStart returns the byte offset into the contents of the start of the
+// provided (1-based) line, and false if the contents don't have that many
+// lines.
+func (f *SpecFile) lineStart(line int) (int, bool) {
+	offset := 0
+	for current := 1; current < line; current++ {
+		next := bytes.IndexByte(f.contents[offset:], '\n')
+		if next < 0 {
+			return 0, false
+		}
+		offset += next + 1
+	}
+	return offset, true
+}
+
+// offset returns the byte offset into the contents of the node's position.
+func (f *SpecFile) offset(node *yaml.Node) (int, error) {
+	lineStart, ok := f.lineStart(node.Line)
+	if !ok {
+		return 0, fmt.Errorf("line %d is past the end of spec file '%s'", node.Line, f.Path)
+	}
+
+	// Columns count characters, not bytes
+	offset := lineStart
+	for column := 1; column < node.Column; column++ {
+		_, size := utf8.DecodeRune(f.contents[offset:])
+		if size == 0 {
+			return 0, fmt.Errorf("column %d is past the end of line %d in spec file '%s'", node.Column, node.Line, f.Path)
+		}
+		offset += size
+	}
+
+	return offset, nil
+}
+
+// scalarEnd returns the byte offset into the contents just past the end of the
+// scalar node that starts at the provided offset.
+func (f *SpecFile) scalarEnd(node *yaml.Node, start int) (int, error) {
+	rest := f.contents[start:]
+
+	switch node.Style {
+	case yaml.DoubleQuotedStyle:
+		for i := 1; i < len(rest); i++ {
+			switch rest[i] {
+			case '\\':
+				i++
+			case '"':
+				return start + i + 1, nil
+			}
+		}
+	case yaml.SingleQuotedStyle:
+		for i := 1; i < len(rest); i++ {
+			if rest[i] != '\'' {
+				continue
+			}
+			if i+1 < len(rest) && rest[i+1] == '\'' {
+				i++
+				continue
+			}
+			return start + i + 1, nil
+		}
+	case 0: // plain
+		if bytes.HasPrefix(rest, []byte(node.Value)) {
+			return start + len(node.Value), nil
+		}
+	}
+
+	return 0, fmt.Errorf("can't edit the value '%s' on line %d of spec file '%s', since it's not written as a single-line scalar", node.Value, node.Line, f.Path)
+}
+
+// replaceScalar replaces the scalar node's value in the contents, keeping its
+// quoting style where possible.
+func (f *SpecFile) replaceScalar(node *yaml.Node, value string) error {
+	if node.Kind != yaml.ScalarNode {
+		return fmt.Errorf("can't edit the value on line %d of spec file '%s', since it's not a scalar", node.Line, f.Path)
+	}
+
+	start, err := f.offset(node)
+	if err != nil {
+		return err
+	}
+	end, err := f.scalarEnd(node, start)
+	if err != nil {
+		return err
+	}
+
+	message.Debugf("replacing '%s' with '%s' on line %d of spec file '%s'", string(f.contents[start:end]), value, node.Line, f.Path)
+	var contents []byte
+	contents = append(contents, f.contents[:start]...)
+	contents = append(contents, formatScalar(value, node.Style)...)
+	contents = append(contents, f.contents[end:]...)
+
+	return f.setContents(contents)
+}
+
+// addScalar adds a new key to the mapping node, on its own line right after
+// the mapping's 'remote' key.
+func (f *SpecFile) addScalar(node *yaml.Node, key, value string) error {
+	if node.Style&yaml.FlowStyle != 0 {
+		return fmt.Errorf("can't add '%s' to the remote on line %d of spec file '%s', since it's not written in YAML's block style", key, node.Line, f.Path)
+	}
+
+	var anchorKey, anchorValue *yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "remote" {
+			anchorKey, anchorValue = node.Content[i], node.Content[i+1]
+		}
+	}
+	if anchorKey == nil || anchorValue.Kind != yaml.ScalarNode {
+		return fmt.Errorf("can't add '%s' to the remote on line %d of spec file '%s', since it has no 'remote' key to add it after", key, node.Line, f.Path)
+	}
+
+	line := fmt.Sprintf("%s%s: %s\n", strings.Repeat(" ", anchorKey.Column-1), key, formatScalar(value, anchorValue.Style))
+
+	var contents []byte
+	nextLine, ok := f.lineStart(anchorValue.Line + 1)
+	if ok {
+		contents = append(contents, f.contents[:nextLine]...)
+		contents = append(contents, line...)
+		contents = append(contents, f.contents[nextLine:]...)
+	} else {
+		// The 'remote' key is on the last line, without a trailing newline
+		contents = append(contents, f.contents...)
+		contents = append(contents, '\n')
+		contents = append(contents, line...)
+	}
+
+	return f.setContents(contents)
+}
+
+// AddRemote appends the remote to the specfile's list of remotes. If the
+// remotes already there are written in YAML's block style, the new one is
+// added as new lines after them, indented to match. Otherwise (like for JSON
+// specfiles, or an empty list), the whole specfile is re-written; see
+// [SpecFile.rewriteRemotes].
+func (f *SpecFile) AddRemote(remote Remote) error {
+	if f.isBlockStyle() {
+		return f.appendBlockRemote(remote)
+	}
+
+	spec, err := f.Spec()
+	if err != nil {
+		return err
+	}
+
+	return f.rewriteRemotes(append(spec.Remotes, remote))
+}
+
+// RemoveRemote removes the remote at the provided index from the specfile's
+// list of remotes, along with any comment lines directly above it. Like
+// [SpecFile.AddRemote], the whole specfile is re-written if the list isn't
+// written in YAML's block style (or if it would become empty).
+func (f *SpecFile) RemoveRemote(remoteIndex int) error {
+	remotesNode, err := f.remotesNode()
+	if err != nil {
+		return err
+	}
+	if remoteIndex < 0 || remoteIndex >= len(remotesNode.Content) {
+		return fmt.Errorf("spec file '%s' has no remote #%d", f.Path, remoteIndex)
+	}
+
+	if !f.isBlockStyle() || len(remotesNode.Content) == 1 {
+		spec, err := f.Spec()
+		if err != nil {
+			return err
+		}
+		return f.rewriteRemotes(append(spec.Remotes[:remoteIndex:remoteIndex], spec.Remotes[remoteIndex+1:]...))
+	}
+
+	items := remotesNode.Content
+	start := f.itemStartLine(items[remoteIndex])
+	var end int
+	if remoteIndex+1 < len(items) {
+		end = f.itemStartLine(items[remoteIndex+1])
+	} else {
+		// The separating blank lines above the last remote go with it
+		end = f.contentEndLine()
+		for start > 1 && f.isBlankLine(start-1) {
+			start--
+		}
+	}
+
+	startOffset, _ := f.lineStart(start)
+	endOffset, ok := f.lineStart(end)
+	if !ok {
+		endOffset = len(f.contents)
+	}
+
+	var contents []byte
+	contents = append(contents, f.contents[:startOffset]...)
+	contents = append(contents, f.contents[endOffset:]...)
+
+	return f.setContents(contents)
+}
+
+// isBlockStyle reports whether the specfile's list of remotes is written in
+// YAML's block style, with at least one remote in it whose dash starts its
+// line, so that it can be edited line by line.
+func (f *SpecFile) isBlockStyle() bool {
+	remotesNode, err := f.remotesNode()
+	if err != nil || remotesNode.Style&yaml.FlowStyle != 0 || len(remotesNode.Content) == 0 {
+		return false
+	}
+
+	for _, item := range remotesNode.Content {
+		if _, ok := f.itemPrefix(item); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// itemPrefix returns the text on the item's first line that comes before it
+// (like "  - "), and false if that's anything other than the item's dash &
+// indentation.
+func (f *SpecFile) itemPrefix(item *yaml.Node) (string, bool) {
+	lineStart, ok := f.lineStart(item.Line)
+	if !ok {
+		return "", false
+	}
+	itemStart, err := f.offset(item)
+	if err != nil {
+		return "", false
+	}
+
+	prefix := string(f.contents[lineStart:itemStart])
+	return prefix, strings.TrimSpace(prefix) == "-"
+}
+
+// line returns the text of the provided (1-based) line, without its newline.
+func (f *SpecFile) line(line int) string {
+	start, ok := f.lineStart(line)
+	if !ok {
+		return ""
+	}
+	end := bytes.IndexByte(f.contents[start:], '\n')
+	if end < 0 {
+		return string(f.contents[start:])
+	}
+	return string(f.contents[start : start+end])
+}
+
+// isBlankLine reports whether the provided line has nothing but whitespace.
+func (f *SpecFile) isBlankLine(line int) bool {
+	return strings.TrimSpace(f.line(line)) == ""
+}
+
+// isCommentLine reports whether the provided line has nothing but a comment.
+func (f *SpecFile) isCommentLine(line int) bool {
+	return strings.HasPrefix(strings.TrimSpace(f.line(line)), "#")
+}
+
+// itemStartLine returns the first line of the item, including any comment
+// lines directly above it.
+func (f *SpecFile) itemStartLine(item *yaml.Node) int {
+	start := item.Line
+	for start > 1 && f.isCommentLine(start-1) {
+		start--
+	}
+	return start
+}
+
+// contentEndLine returns the line just after the last remote in the list, not
+// counting any blank lines after it, or any comments that are indented less
+// than the list is (and so aren't part of it).
+func (f *SpecFile) contentEndLine() int {
+	remotesNode, err := f.remotesNode()
+	if err != nil {
+		return 1
+	}
+	prefix, _ := f.itemPrefix(remotesNode.Content[len(remotesNode.Content)-1])
+	listIndent := len(prefix) - len(strings.TrimLeft(prefix, " "))
+	isOutsideComment := func(line int) bool {
+		text := f.line(line)
+		return f.isCommentLine(line) && len(text)-len(strings.TrimLeft(text, " ")) < listIndent
+	}
+
+	// The list ends where the next top-level key starts, if there is one
+	root := f.root.Content[0]
+	end := strings.Count(string(f.contents), "\n") + 2
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "remotes" {
+			continue
+		}
+		if i+2 < len(root.Content) {
+			end = f.itemStartLine(root.Content[i+2])
+		}
+	}
+
+	for end > 1 && (f.isBlankLine(end-1) || isOutsideComment(end-1)) {
+		end--
+	}
+	return end
+}
+
+// appendBlockRemote adds the remote as new lines after the last remote in a
+// block-style list, matching its indentation and its spacing from the remote
+// before it.
+func (f *SpecFile) appendBlockRemote(remote Remote) error {
+	remotesNode, err := f.remotesNode()
+	if err != nil {
+		return err
+	}
+	items := remotesNode.Content
+	last := items[len(items)-1]
+	prefix, _ := f.itemPrefix(last)
+
+	var encoded bytes.Buffer
+	encoder := yaml.NewEncoder(&encoded)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(remote); err != nil {
+		return fmt.Errorf("encoding remote: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("encoding remote: %w", err)
+	}
+
+	var text strings.Builder
+	if len(items) > 1 && f.isBlankLine(f.itemStartLine(last)-1) {
+		text.WriteString("\n")
+	}
+	indent := strings.Repeat(" ", len(prefix))
+	for i, line := range strings.Split(strings.TrimSuffix(encoded.String(), "\n"), "\n") {
+		if i == 0 {
+			text.WriteString(prefix + line + "\n")
+		} else {
+			text.WriteString(indent + line + "\n")
+		}
+	}
+
+	offset, ok := f.lineStart(f.contentEndLine())
+	var contents []byte
+	if ok {
+		contents = append(contents, f.contents[:offset]...)
+		contents = append(contents, text.String()...)
+		contents = append(contents, f.contents[offset:]...)
+	} else {
+		// The last remote is on the last line, without a trailing newline
+		contents = append(contents, f.contents...)
+		contents = append(contents, '\n')
+		contents = append(contents, text.String()...)
+	}
+
+	return f.setContents(contents)
+}
+
+// rewriteRemotes replaces the specfile's whole list of remotes, and re-writes
+// the specfile. JSON specfiles are re-written as JSON, which loses their
+// formatting. YAML specfiles keep their comments (besides any on the remotes
+// themselves), but the rest of their formatting is normalized.
+func (f *SpecFile) rewriteRemotes(remotes []Remote) error {
+	if remotes == nil {
+		remotes = []Remote{}
+	}
+
+	if trimmed := bytes.TrimSpace(f.contents); len(trimmed) > 0 && trimmed[0] == '{' {
+		spec, err := f.Spec()
+		if err != nil {
+			return err
+		}
+		spec.Remotes = remotes
+		contents, err := json.MarshalIndent(spec, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encoding spec file as JSON: %w", err)
+		}
+		return f.setContents(append(contents, '\n'))
+	}
+
+	var remotesNode yaml.Node
+	if err := remotesNode.Encode(remotes); err != nil {
+		return fmt.Errorf("encoding remotes: %w", err)
+	}
+
+	document := f.root
+	if len(document.Content) == 0 {
+		document = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := document.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("spec file '%s' isn't a mapping", f.Path)
+	}
+
+	replaced := false
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "remotes" {
+			root.Content[i+1] = &remotesNode
+			replaced = true
+		}
+	}
+	if !replaced {
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "remotes"}, &remotesNode)
+	}
+
+	var contents bytes.Buffer
+	encoder := yaml.NewEncoder(&contents)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&document); err != nil {
+		return fmt.Errorf("encoding spec file: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("encoding spec file: %w", err)
+	}
+
+	return f.setContents(contents.Bytes())
+}
+
+// formatScalar writes the value as a YAML scalar in the provided style, falling
+// back to double quotes (which are also valid JSON) if the value can't be
+// written in that style as-is.
+func formatScalar(value string, style yaml.Style) string {
+	switch style {
+	case yaml.SingleQuotedStyle:
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	case 0: // plain
+		if isPlainSafe(value) {
+			return value
+		}
+	}
+
+	// Encoding a string can't fail
+	var quoted bytes.Buffer
+	encoder := json.NewEncoder(&quoted)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(value)
+	return strings.TrimSuffix(quoted.String(), "\n")
+}
+
+// isPlainSafe reports whether the value would be read back as the same string
+// if it were written unquoted.
+func isPlainSafe(value string) bool {
+	if value == "" || strings.ContainsAny(value, "\n#") {
+		return false
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal([]byte("key: "+value), &node); err != nil {
+		return false
+	}
+	valueNode := mappingValue(node.Content[0], "key")
+
+	return valueNode != nil && valueNode.Kind == yaml.ScalarNode && valueNode.Tag == "!!str" && valueNode.Value == value
+}
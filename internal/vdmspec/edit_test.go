@@ -0,0 +1,273 @@
+package vdmspec
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestSpecFile(t *testing.T, name, contents string) *SpecFile {
+	t.Helper()
+
+	specFilePath := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(specFilePath, []byte(contents), 0644))
+
+	specFile, err := ReadSpecFile(specFilePath)
+	require.NoError(t, err)
+
+	return specFile
+}
+
+func TestSpecFileSetVersion(t *testing.T) {
+	t.Run("only the version changes in YAML", func(t *testing.T) {
+		specFile := writeTestSpecFile(t, "vdm.yaml", `# my deps
+remotes:
+
+  - type:       "git" # the default
+    remote:     "https://github.com/opensourcecorp/go-common"
+    local_path: "./deps/go-common"
+    version:    "v0.2.0" # pinned for now
+
+  - remote: https://github.com/opensourcecorp/other
+    version: v1.0.0
+    local_path: ./deps/other
+
+  - remote: 'https://github.com/opensourcecorp/quoted'
+    version: 'v1.0.0'
+    local_path: './deps/quoted'
+`)
+
+		require.NoError(t, specFile.SetVersion(0, "v0.3.0"))
+		require.NoError(t, specFile.SetVersion(1, ">=1.2.0 <2"))
+		require.NoError(t, specFile.SetVersion(2, "it's"))
+
+		assert.Equal(t, `# my deps
+remotes:
+
+  - type:       "git" # the default
+    remote:     "https://github.com/opensourcecorp/go-common"
+    local_path: "./deps/go-common"
+    version:    "v0.3.0" # pinned for now
+
+  - remote: https://github.com/opensourcecorp/other
+    version: ">=1.2.0 <2"
+    local_path: ./deps/other
+
+  - remote: 'https://github.com/opensourcecorp/quoted'
+    version: 'it''s'
+    local_path: './deps/quoted'
+`, string(specFile.Bytes()))
+
+		spec, err := specFile.Spec()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"v0.3.0", ">=1.2.0 <2", "it's"}, []string{spec.Remotes[0].Version, spec.Remotes[1].Version, spec.Remotes[2].Version})
+	})
+
+	t.Run("only the version changes in JSON", func(t *testing.T) {
+		specFile := writeTestSpecFile(t, "vdm.json", `{
+  "remotes": [
+    {"remote": "https://github.com/opensourcecorp/go-common", "version": "v0.2.0", "local_path": "./deps/go-common"}
+  ]
+}
+`)
+
+		require.NoError(t, specFile.SetVersion(0, `v0.3.0"`))
+		assert.Equal(t, `{
+  "remotes": [
+    {"remote": "https://github.com/opensourcecorp/go-common", "version": "v0.3.0\"", "local_path": "./deps/go-common"}
+  ]
+}
+`, string(specFile.Bytes()))
+	})
+
+	t.Run("adds a missing version after the remote", func(t *testing.T) {
+		specFile := writeTestSpecFile(t, "vdm.yaml", `remotes:
+  - local_path: ./deps/go-common
+    remote: https://github.com/opensourcecorp/go-common
+  - remote: "https://github.com/opensourcecorp/last"`)
+
+		require.NoError(t, specFile.SetVersion(0, "v0.2.0"))
+		require.NoError(t, specFile.SetVersion(1, "main"))
+		assert.Equal(t, `remotes:
+  - local_path: ./deps/go-common
+    remote: https://github.com/opensourcecorp/go-common
+    version: v0.2.0
+  - remote: "https://github.com/opensourcecorp/last"
+    version: "main"
+`, string(specFile.Bytes()))
+	})
+
+	t.Run("can't add a version to a flow-style remote", func(t *testing.T) {
+		specFile := writeTestSpecFile(t, "vdm.json", `{"remotes": [{"remote": "https://github.com/opensourcecorp/go-common", "local_path": "./deps/go-common"}]}`)
+		assert.Error(t, specFile.SetVersion(0, "v0.2.0"))
+	})
+
+	t.Run("fails on unknown remotes", func(t *testing.T) {
+		specFile := writeTestSpecFile(t, "vdm.yaml", "remotes: []\n")
+		assert.Error(t, specFile.SetVersion(0, "v0.2.0"))
+	})
+
+	t.Run("writes back to disk", func(t *testing.T) {
+		specFile := writeTestSpecFile(t, "vdm.yaml", "remotes:\n  - remote: https://some-remote\n    version: v1\n")
+		require.NoError(t, specFile.SetVersion(0, "v2"))
+		require.NoError(t, specFile.Write())
+
+		spec, err := GetSpecFromFile(specFile.Path)
+		require.NoError(t, err)
+		assert.Equal(t, "v2", spec.Remotes[0].Version)
+	})
+}
+
+func TestSpecFileAddRemote(t *testing.T) {
+	newRemote := Remote{Remote: "https://github.com/opensourcecorp/new", Version: "v1.0.0", LocalPath: "./deps/new", Exclude: []string{"docs"}}
+
+	t.Run("appends to a block list, matching its style", func(t *testing.T) {
+		specFile := writeTestSpecFile(t, "vdm.yaml", `# my deps
+remotes:
+
+  - remote:     "https://github.com/opensourcecorp/go-common"
+    local_path: "./deps/go-common"
+    version:    "v0.2.0" # pinned
+
+  # the http proto
+  - type:       "file"
+    remote:     "https://example.com/http.proto"
+    local_path: "./deps/http.proto"
+
+# trailing comment
+`)
+
+		require.NoError(t, specFile.AddRemote(newRemote))
+		assert.Equal(t, `# my deps
+remotes:
+
+  - remote:     "https://github.com/opensourcecorp/go-common"
+    local_path: "./deps/go-common"
+    version:    "v0.2.0" # pinned
+
+  # the http proto
+  - type:       "file"
+    remote:     "https://example.com/http.proto"
+    local_path: "./deps/http.proto"
+
+  - remote: https://github.com/opensourcecorp/new
+    version: v1.0.0
+    local_path: ./deps/new
+    exclude:
+      - docs
+
+# trailing comment
+`, string(specFile.Bytes()))
+
+		spec, err := specFile.Spec()
+		require.NoError(t, err)
+		require.Len(t, spec.Remotes, 3)
+		assert.True(t, newRemote.Equal(spec.Remotes[2]))
+	})
+
+	t.Run("appends without a trailing newline", func(t *testing.T) {
+		specFile := writeTestSpecFile(t, "vdm.yaml", "remotes:\n- remote: https://some-remote\n  local_path: ./deps/some-remote")
+		require.NoError(t, specFile.AddRemote(Remote{Remote: "https://other-remote", LocalPath: "./deps/other-remote"}))
+		assert.Equal(t, "remotes:\n- remote: https://some-remote\n  local_path: ./deps/some-remote\n- remote: https://other-remote\n  local_path: ./deps/other-remote\n", string(specFile.Bytes()))
+	})
+
+	t.Run("fills an empty list, keeping comments", func(t *testing.T) {
+		specFile := writeTestSpecFile(t, "vdm.yaml", "# my deps\nremotes: []\n")
+		require.NoError(t, specFile.AddRemote(newRemote))
+
+		assert.Contains(t, string(specFile.Bytes()), "# my deps\n")
+		spec, err := specFile.Spec()
+		require.NoError(t, err)
+		require.Len(t, spec.Remotes, 1)
+		assert.True(t, newRemote.Equal(spec.Remotes[0]))
+	})
+
+	t.Run("re-writes JSON as JSON", func(t *testing.T) {
+		specFile := writeTestSpecFile(t, "vdm.json", `{"remotes": [{"remote": "https://some-remote", "version": "v1", "local_path": "./deps/some-remote"}]}`)
+		require.NoError(t, specFile.AddRemote(newRemote))
+
+		assert.True(t, strings.HasPrefix(string(specFile.Bytes()), "{\n  \"remotes\": ["))
+		spec, err := specFile.Spec()
+		require.NoError(t, err)
+		require.Len(t, spec.Remotes, 2)
+		assert.True(t, newRemote.Equal(spec.Remotes[1]))
+	})
+}
+
+func TestSpecFileRemoveRemote(t *testing.T) {
+	contents := `# my deps
+remotes:
+
+  # first
+  - remote: https://first
+    local_path: ./deps/first
+
+  # second
+  - remote: https://second
+    local_path: ./deps/second
+
+  - remote: https://third
+    local_path: ./deps/third
+
+other: thing
+`
+
+	for remoteIndex, want := range map[int]string{
+		0: `# my deps
+remotes:
+
+  # second
+  - remote: https://second
+    local_path: ./deps/second
+
+  - remote: https://third
+    local_path: ./deps/third
+
+other: thing
+`,
+		1: `# my deps
+remotes:
+
+  # first
+  - remote: https://first
+    local_path: ./deps/first
+
+  - remote: https://third
+    local_path: ./deps/third
+
+other: thing
+`,
+		2: `# my deps
+remotes:
+
+  # first
+  - remote: https://first
+    local_path: ./deps/first
+
+  # second
+  - remote: https://second
+    local_path: ./deps/second
+
+other: thing
+`,
+	} {
+		specFile := writeTestSpecFile(t, "vdm.yaml", contents)
+		require.NoError(t, specFile.RemoveRemote(remoteIndex))
+		assert.Equal(t, want, string(specFile.Bytes()), remoteIndex)
+	}
+
+	t.Run("removing the last remote leaves an empty list", func(t *testing.T) {
+		specFile := writeTestSpecFile(t, "vdm.yaml", "# my deps\nremotes:\n  - remote: https://only\n    local_path: ./deps/only\n")
+		require.NoError(t, specFile.RemoveRemote(0))
+		assert.Equal(t, "# my deps\nremotes: []\n", string(specFile.Bytes()))
+	})
+
+	t.Run("fails on unknown remotes", func(t *testing.T) {
+		specFile := writeTestSpecFile(t, "vdm.yaml", contents)
+		assert.Error(t, specFile.RemoveRemote(3))
+	})
+}
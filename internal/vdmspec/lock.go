@@ -0,0 +1,123 @@
+package vdmspec
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/opensourcecorp/vdm/internal/message"
+	"gopkg.in/yaml.v3"
+)
+
+// LockFileName is the name of the lockfile, kept alongside the specfile, that
+// pins exactly what was retrieved for each remote.
+const LockFileName string = "vdm.lock"
+
+// Lock defines the structure of the vdm lockfile.
+type Lock struct {
+	Remotes []LockedRemote `json:"remotes" yaml:"remotes"`
+}
+
+// LockedRemote records what was actually retrieved for a remote, so that later
+// syncs can retrieve exactly the same content.
+type LockedRemote struct {
+	Remote `yaml:",inline"`
+	// Commit is the full commit hash that a git remote's version resolved to.
+	Commit string `json:"commit,omitempty" yaml:"commit,omitempty"`
+	// Tag is the tag that a git remote's version constraint resolved to.
+	Tag string `json:"tag,omitempty" yaml:"tag,omitempty"`
+	// URL is the final URL a file remote was retrieved from, after any
+	// redirects.
+	URL string `json:"url,omitempty" yaml:"url,omitempty"`
+	// SHA256 is the digest of a file remote's contents, as retrieved. This
+	// shadows the remote's own (optional) pinned digest, which is still
+	// available via the embedded [Remote].
+	SHA256 string `json:"content_sha256,omitempty" yaml:"content_sha256,omitempty"`
+	// FetchedAt is when the remote was retrieved.
+	FetchedAt time.Time `json:"fetched_at" yaml:"fetched_at"`
+}
+
+// IsZero reports whether the locked remote is actually populated.
+func (l LockedRemote) IsZero() bool {
+	return l.Remote.IsZero() &&
+		l.Commit == "" &&
+		l.Tag == "" &&
+		l.URL == "" &&
+		l.SHA256 == "" &&
+		l.FetchedAt.IsZero()
+}
+
+// LockFilePath returns the path of the lockfile that belongs to the specfile at
+// the provided path.
+func LockFilePath(specFilePath string) string {
+	return filepath.Join(filepath.Dir(specFilePath), LockFileName)
+}
+
+// GetLockFromFile reads the lockfile from disk. A lockfile that doesn't exist
+// yet is not an error, and yields an empty [Lock].
+func GetLockFromFile(lockFilePath string) (Lock, error) {
+	lockFile, err := os.ReadFile(lockFilePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return Lock{}, nil
+	} else if err != nil {
+		return Lock{}, fmt.Errorf("there was a problem reading the %s file from '%s': %w", LockFileName, lockFilePath, err)
+	}
+	message.Debugf("%s contents read:\n%s", LockFileName, string(lockFile))
+
+	var lock Lock
+	err = yaml.Unmarshal(lockFile, &lock)
+	if err != nil {
+		return Lock{}, fmt.Errorf("there was a problem reading the contents of the %s file at '%s': %w", LockFileName, lockFilePath, err)
+	}
+
+	return lock, nil
+}
+
+// WriteToFile writes the lockfile to disk.
+func (l Lock) WriteToFile(lockFilePath string) error {
+	lockContent, err := yaml.Marshal(l)
+	if err != nil {
+		return fmt.Errorf("marshalling %s: %w", LockFileName, err)
+	}
+
+	message.Debugf("writing lockfile to '%s'", lockFilePath)
+	err = os.WriteFile(lockFilePath, lockContent, 0644)
+	if err != nil {
+		return fmt.Errorf("writing lockfile: %w", err)
+	}
+
+	return nil
+}
+
+// Find returns the locked entry for the provided remote. An entry only counts
+// if it was locked from the same source as the remote is currently specified
+// with, since otherwise it's stale.
+func (l Lock) Find(remote Remote) (LockedRemote, bool) {
+	for _, locked := range l.Remotes {
+		if locked.Remote.SameSource(remote) {
+			return locked, true
+		}
+	}
+
+	return LockedRemote{}, false
+}
+
+// SameSource reports whether the two remotes would retrieve the same content to
+// the same place. Settings that only affect how the content is kept on disk
+// aren't considered.
+func (r Remote) SameSource(other Remote) bool {
+	return r.normalizedType() == other.normalizedType() &&
+		r.Remote == other.Remote &&
+		r.Version == other.Version &&
+		filepath.Clean(r.LocalPath) == filepath.Clean(other.LocalPath)
+}
+
+// normalizedType accounts for git being the default remote type.
+func (r Remote) normalizedType() string {
+	if r.Type == "" {
+		return GitType
+	}
+	return r.Type
+}
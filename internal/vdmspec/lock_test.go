@@ -0,0 +1,74 @@
+package vdmspec
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLock(t *testing.T) {
+	lockedRemote := LockedRemote{
+		Remote:    Remote{Remote: "https://some-remote", Version: "main", LocalPath: "./deps/some-remote"},
+		Commit:    "2e6657f5ac013296167c4dd92fbb46f0e3dbdc5f",
+		FetchedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	t.Run("round-trips through disk", func(t *testing.T) {
+		lockFilePath := LockFilePath(filepath.Join(t.TempDir(), "vdm.yaml"))
+
+		lock, err := GetLockFromFile(lockFilePath)
+		require.NoError(t, err)
+		assert.Empty(t, lock.Remotes)
+
+		lock = Lock{Remotes: []LockedRemote{lockedRemote}}
+		require.NoError(t, lock.WriteToFile(lockFilePath))
+
+		got, err := GetLockFromFile(lockFilePath)
+		require.NoError(t, err)
+		assert.Equal(t, lock, got)
+	})
+
+	t.Run("pinned file remote round-trips through disk", func(t *testing.T) {
+		lockFilePath := LockFilePath(filepath.Join(t.TempDir(), "vdm.yaml"))
+
+		pinned := LockedRemote{
+			Remote:    Remote{Type: FileType, Remote: "https://some-remote/some.file", LocalPath: "./deps/some.file", SHA256: strings.Repeat("a", 64)},
+			URL:       "https://some-remote/some.file",
+			SHA256:    strings.Repeat("a", 64),
+			FetchedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		}
+		lock := Lock{Remotes: []LockedRemote{pinned}}
+		require.NoError(t, lock.WriteToFile(lockFilePath))
+
+		got, err := GetLockFromFile(lockFilePath)
+		require.NoError(t, err)
+		assert.Equal(t, lock, got)
+	})
+
+	t.Run("Find", func(t *testing.T) {
+		lock := Lock{Remotes: []LockedRemote{lockedRemote}}
+
+		t.Run("matches the same source", func(t *testing.T) {
+			remote := lockedRemote.Remote
+			remote.Type = GitType
+			remote.LocalPath = "deps/some-remote"
+			remote.KeepGitDir = true
+
+			got, ok := lock.Find(remote)
+			assert.True(t, ok)
+			assert.Equal(t, lockedRemote, got)
+		})
+
+		t.Run("doesn't match a changed version", func(t *testing.T) {
+			remote := lockedRemote.Remote
+			remote.Version = "v1.0.0"
+
+			_, ok := lock.Find(remote)
+			assert.False(t, ok)
+		})
+	})
+}
@@ -0,0 +1,148 @@
+package vdmspec
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Manifest maps each file that vdm placed on disk for a remote (relative to the
+// remote's local path) to the SHA-256 digest of its contents.
+type Manifest map[string]string
+
+// ManifestDiff describes how the content on disk differs from a recorded
+// [Manifest].
+type ManifestDiff struct {
+	Modified []string `json:"modified,omitempty" yaml:"modified,omitempty"`
+	Added    []string `json:"added,omitempty" yaml:"added,omitempty"`
+	Deleted  []string `json:"deleted,omitempty" yaml:"deleted,omitempty"`
+}
+
+// IsEmpty reports whether the diff found no differences.
+func (d ManifestDiff) IsEmpty() bool {
+	return len(d.Modified) == 0 && len(d.Added) == 0 && len(d.Deleted) == 0
+}
+
+// BuildManifest hashes the remote's content as it currently exists on disk. The
+// remote's metafile and any .git directory are not included. A local path that
+// doesn't exist yields an empty manifest.
+func (r Remote) BuildManifest() (Manifest, error) {
+	manifest := make(Manifest)
+
+	info, err := os.Stat(r.LocalPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return manifest, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("checking local path '%s': %w", r.LocalPath, err)
+	}
+
+	if !info.IsDir() {
+		digest, err := hashFile(r.LocalPath)
+		if err != nil {
+			return nil, err
+		}
+		manifest[filepath.Base(r.LocalPath)] = digest
+		return manifest, nil
+	}
+
+	metaFilePath := filepath.Clean(r.MakeMetaFilePath())
+	err = filepath.WalkDir(r.LocalPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if filepath.Clean(path) == metaFilePath {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(r.LocalPath, path)
+		if err != nil {
+			return fmt.Errorf("determining relative path for '%s': %w", path, err)
+		}
+
+		// Symlinks are recorded by their target, since following them could
+		// lead outside of the local path
+		if d.Type()&fs.ModeSymlink != 0 {
+			target, err := os.Readlink(path)
+			if err != nil {
+				return fmt.Errorf("reading symlink '%s': %w", path, err)
+			}
+			manifest[filepath.ToSlash(relPath)] = hashBytes([]byte(target))
+			return nil
+		}
+
+		digest, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		manifest[filepath.ToSlash(relPath)] = digest
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("building manifest for local path '%s': %w", r.LocalPath, err)
+	}
+
+	return manifest, nil
+}
+
+// Diff compares the recorded manifest against the current one, and reports
+// every file that was modified, added, or deleted since it was recorded.
+func (m Manifest) Diff(current Manifest) ManifestDiff {
+	var diff ManifestDiff
+
+	for path, digest := range m {
+		currentDigest, ok := current[path]
+		if !ok {
+			diff.Deleted = append(diff.Deleted, path)
+		} else if currentDigest != digest {
+			diff.Modified = append(diff.Modified, path)
+		}
+	}
+	for path := range current {
+		if _, ok := m[path]; !ok {
+			diff.Added = append(diff.Added, path)
+		}
+	}
+
+	sort.Strings(diff.Modified)
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Deleted)
+
+	return diff
+}
+
+func hashFile(path string) (digest string, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("opening '%s' for hashing: %w", path, err)
+	}
+	defer func() {
+		if closeErr := f.Close(); closeErr != nil {
+			err = errors.Join(err, fmt.Errorf("closing '%s' after hashing: %w", path, closeErr))
+		}
+	}()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return "", fmt.Errorf("hashing '%s': %w", path, err)
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+func hashBytes(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,67 @@
+package vdmspec
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifest(t *testing.T) {
+	t.Run("BuildManifest for a directory", func(t *testing.T) {
+		remote := Remote{
+			Remote:    "https://some-remote",
+			Version:   "v1.0.0",
+			LocalPath: t.TempDir(),
+		}
+		require.NoError(t, os.MkdirAll(filepath.Join(remote.LocalPath, "sub", ".git"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(remote.LocalPath, "a.txt"), []byte("a"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(remote.LocalPath, "sub", "b.txt"), []byte("b"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(remote.LocalPath, "sub", ".git", "HEAD"), []byte("ref"), 0644))
+		require.NoError(t, os.WriteFile(remote.MakeMetaFilePath(), []byte("meta"), 0644))
+
+		manifest, err := remote.BuildManifest()
+		require.NoError(t, err)
+		assert.Equal(t, Manifest{
+			"a.txt":     hashBytes([]byte("a")),
+			"sub/b.txt": hashBytes([]byte("b")),
+		}, manifest)
+	})
+
+	t.Run("BuildManifest for a file", func(t *testing.T) {
+		remote := Remote{
+			Type:      FileType,
+			Remote:    "https://some-remote/a.txt",
+			LocalPath: filepath.Join(t.TempDir(), "a.txt"),
+		}
+		require.NoError(t, os.WriteFile(remote.LocalPath, []byte("a"), 0644))
+
+		manifest, err := remote.BuildManifest()
+		require.NoError(t, err)
+		assert.Equal(t, Manifest{"a.txt": hashBytes([]byte("a"))}, manifest)
+	})
+
+	t.Run("BuildManifest for a missing path", func(t *testing.T) {
+		remote := Remote{LocalPath: filepath.Join(t.TempDir(), "nope")}
+
+		manifest, err := remote.BuildManifest()
+		require.NoError(t, err)
+		assert.Empty(t, manifest)
+	})
+
+	t.Run("Diff", func(t *testing.T) {
+		recorded := Manifest{"same": "1", "changed": "2", "gone": "3"}
+		current := Manifest{"same": "1", "changed": "22", "new": "4"}
+
+		diff := recorded.Diff(current)
+		assert.Equal(t, ManifestDiff{
+			Modified: []string{"changed"},
+			Added:    []string{"new"},
+			Deleted:  []string{"gone"},
+		}, diff)
+		assert.False(t, diff.IsEmpty())
+		assert.True(t, recorded.Diff(recorded).IsEmpty())
+	})
+}
@@ -0,0 +1,325 @@
+package vdmspec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// SchemaID is the URL that the spec file's JSON Schema is published at, from
+// the root of the vdm repository.
+const SchemaID string = "https://raw.githubusercontent.com/opensourcecorp/vdm/main/vdm.schema.json"
+
+// schemaFields holds the description of every field of [Spec] and [Remote] in
+// the JSON Schema, keyed by their JSON names, along with any other keywords that
+// constrain them beyond their Go types. Every field must have an entry, so that
+// one can't be added to the spec without being documented in the schema.
+var schemaFields = map[string]map[string]any{
+	"remotes": {
+		"description": "The remotes to retrieve, and where to put them.",
+		// An empty 'remotes:' is null
+		"type": []string{"array", "null"},
+	},
+	"name": {
+		"description": "A unique name for the remote, to refer to it by in logs and on the command line. Defaults to the base name of 'local_path'.",
+		"pattern":     nameRegex.String(),
+	},
+	"type": {
+		"description": "The type of the remote.",
+		"enum":        []string{GitType, FileType},
+		"default":     GitType,
+	},
+	"remote": {
+		"description": "The URL of the remote.",
+		"minLength":   1,
+		"pattern":     protocolRegex.String(),
+	},
+	"version": {
+		"description": "For git remotes, the tag, branch, or commit to retrieve, 'latest' for the default branch, or a version constraint like '^1.2' to resolve against the remote's semver tags.",
+	},
+	"local_path": {
+		"description": "Where to put the remote, relative to the directory that vdm is run from.",
+		"minLength":   1,
+	},
+	"keep_git_dir": {
+		"description": "For git remotes, keep the .git directory, so that the local copy can be worked on like any other clone.",
+	},
+	"subdir": {
+		"description": "For git remotes, the directory within the repository to retrieve, instead of all of it.",
+	},
+	"sha256": {
+		"description": "For file remotes, the expected SHA-256 digest of the file, as a hex string.",
+		"pattern":     "^[0-9a-fA-F]{64}$",
+	},
+	"sha512": {
+		"description": "For file remotes, the expected SHA-512 digest of the file, as a hex string.",
+		"pattern":     "^[0-9a-fA-F]{128}$",
+	},
+	"archive": {
+		"description": fmt.Sprintf("For file remotes, the archive format to unpack the file from. Detected if unset; set to '%s' to keep an archive as-is.", ArchiveNone),
+		"enum":        []string{"", ArchiveNone, ArchiveTar, ArchiveTarGz, ArchiveTarXz, ArchiveTarBz2, ArchiveTarZst, ArchiveZip},
+	},
+	"strip_components": {
+		"description": "For file remotes, the number of leading path components to remove from each entry when unpacking an archive.",
+		"minimum":     0,
+	},
+	"include": {
+		"description": "Glob patterns (which support '**') for the paths to keep, relative to 'local_path'.",
+		"items":       map[string]any{"type": "string", "minLength": 1},
+	},
+	"exclude": {
+		"description": "Glob patterns (which support '**') for the paths to remove, relative to 'local_path'.",
+		"items":       map[string]any{"type": "string", "minLength": 1},
+	},
+	"prerelease": {
+		"description": "For git remotes with a version constraint, allow pre-release tags to satisfy it.",
+	},
+	"allow_outside_project": {
+		"description": "Allow 'local_path' to be absolute, or outside of the directory that vdm is run from.",
+	},
+	"tags": {
+		"description": "Labels for the remote, so that groups of remotes can be selected on the command line with --tag.",
+		"items":       map[string]any{"type": "string", "pattern": `\S`},
+	},
+}
+
+// remoteSchemaRules are the rules for remotes that depend on more than one of
+// their fields, like which fields each remote type can set.
+var remoteSchemaRules = []any{
+	map[string]any{
+		"if": map[string]any{
+			"properties": map[string]any{"type": map[string]any{"const": FileType}},
+			"required":   []string{"type"},
+		},
+		"then": map[string]any{
+			"properties": map[string]any{
+				"subdir":     map[string]any{"const": ""},
+				"prerelease": map[string]any{"const": false},
+			},
+		},
+		"else": map[string]any{
+			"properties": map[string]any{
+				"sha256":           map[string]any{"const": ""},
+				"sha512":           map[string]any{"const": ""},
+				"archive":          map[string]any{"const": ""},
+				"strip_components": map[string]any{"const": 0},
+			},
+		},
+	},
+	// Like [Spec.Validate], this only applies to remotes that set their type
+	map[string]any{
+		"if": map[string]any{
+			"properties": map[string]any{"type": map[string]any{"const": GitType}},
+			"required":   []string{"type"},
+		},
+		"then": map[string]any{
+			"required":   []string{"version"},
+			"properties": map[string]any{"version": map[string]any{"minLength": 1}},
+		},
+	},
+	map[string]any{
+		"if": map[string]any{
+			"properties": map[string]any{"keep_git_dir": map[string]any{"const": true}},
+			"required":   []string{"keep_git_dir"},
+		},
+		"then": map[string]any{
+			"properties": map[string]any{
+				"subdir":  map[string]any{"const": ""},
+				"include": map[string]any{"maxItems": 0},
+				"exclude": map[string]any{"maxItems": 0},
+			},
+		},
+	},
+	map[string]any{
+		"if": map[string]any{
+			"properties": map[string]any{"archive": map[string]any{"const": ArchiveNone}},
+			"required":   []string{"archive"},
+		},
+		"then": map[string]any{
+			"properties": map[string]any{"strip_components": map[string]any{"const": 0}},
+		},
+	},
+}
+
+// Schema returns the JSON Schema for spec files, generated from the fields of
+// [Spec] and [Remote].
+func Schema() ([]byte, error) {
+	specSchema, err := structSchema(reflect.TypeOf(Spec{}))
+	if err != nil {
+		return nil, err
+	}
+	remoteSchema, err := structSchema(reflect.TypeOf(Remote{}))
+	if err != nil {
+		return nil, err
+	}
+	remoteSchema["allOf"] = remoteSchemaRules
+
+	specSchema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	specSchema["$id"] = SchemaID
+	specSchema["title"] = "vdm spec file"
+	specSchema["$defs"] = map[string]any{"remote": remoteSchema}
+
+	schema, err := json.MarshalIndent(specSchema, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshalling spec schema to JSON: %w", err)
+	}
+
+	return append(schema, '\n'), nil
+}
+
+// structSchema returns the schema for a struct's exported fields, using their
+// JSON names. Fields that aren't omitted when empty are required.
+func structSchema(structType reflect.Type) (map[string]any, error) {
+	properties := make(map[string]any)
+	required := []string{}
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+
+		property, err := typeSchema(field.Type)
+		if err != nil {
+			return nil, fmt.Errorf("generating schema for field '%s' of %s: %w", name, structType.Name(), err)
+		}
+		keywords, ok := schemaFields[name]
+		if !ok {
+			return nil, fmt.Errorf("generating schema for field '%s' of %s: it has no description", name, structType.Name())
+		}
+		for keyword, value := range keywords {
+			property[keyword] = value
+		}
+		properties[name] = property
+
+		if options != "omitempty" {
+			required = append(required, name)
+		}
+	}
+
+	return map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}, nil
+}
+
+// typeSchema returns the schema for a field's Go type.
+func typeSchema(fieldType reflect.Type) (map[string]any, error) {
+	switch fieldType.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}, nil
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Int:
+		return map[string]any{"type": "integer"}, nil
+	case reflect.Struct:
+		return map[string]any{"$ref": "#/$defs/" + strings.ToLower(fieldType.Name())}, nil
+	case reflect.Slice:
+		items, err := typeSchema(fieldType.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	default:
+		return nil, fmt.Errorf("type %s isn't supported", fieldType)
+	}
+}
+
+var (
+	// compiledSchema is the spec file's JSON Schema, compiled once by
+	// [compileSchema].
+	compiledSchema     *jsonschema.Schema
+	compiledSchemaErr  error
+	compiledSchemaOnce sync.Once
+)
+
+// compileSchema compiles the spec file's JSON Schema the first time it's
+// called, and returns the same result every time after that.
+func compileSchema() (*jsonschema.Schema, error) {
+	compiledSchemaOnce.Do(func() {
+		schema, err := Schema()
+		if err != nil {
+			compiledSchemaErr = err
+			return
+		}
+		compiler := jsonschema.NewCompiler()
+		if err := compiler.AddResource(SchemaID, bytes.NewReader(schema)); err != nil {
+			compiledSchemaErr = fmt.Errorf("adding spec schema: %w", err)
+			return
+		}
+		compiledSchema, compiledSchemaErr = compiler.Compile(SchemaID)
+		if compiledSchemaErr != nil {
+			compiledSchemaErr = fmt.Errorf("compiling spec schema: %w", compiledSchemaErr)
+		}
+	})
+
+	return compiledSchema, compiledSchemaErr
+}
+
+// validateSchema checks the spec against its JSON Schema, and returns each
+// failure.
+func (spec Spec) validateSchema() []error {
+	compiled, err := compileSchema()
+	if err != nil {
+		return []error{err}
+	}
+
+	specJSON, err := json.Marshal(spec)
+	if err != nil {
+		return []error{fmt.Errorf("marshalling spec to JSON: %w", err)}
+	}
+	var instance any
+	if err := json.Unmarshal(specJSON, &instance); err != nil {
+		return []error{fmt.Errorf("unmarshalling spec from JSON: %w", err)}
+	}
+
+	var validationErr *jsonschema.ValidationError
+	if err := compiled.Validate(instance); err == nil {
+		return nil
+	} else if !errors.As(err, &validationErr) {
+		return []error{fmt.Errorf("checking spec against its schema: %w", err)}
+	}
+
+	var failures []error
+	var addFailures func(*jsonschema.ValidationError)
+	addFailures = func(validationErr *jsonschema.ValidationError) {
+		if len(validationErr.Causes) > 0 {
+			for _, cause := range validationErr.Causes {
+				addFailures(cause)
+			}
+			return
+		}
+		failures = append(failures, fmt.Errorf("%s: doesn't match the spec file's schema: %s", spec.describeSchemaLocation(validationErr.InstanceLocation), validationErr.Message))
+	}
+	addFailures(validationErr)
+
+	return failures
+}
+
+// describeSchemaLocation describes a location in the spec, given as a JSON
+// Pointer like '/remotes/0/version', for schema validation failures.
+func (spec Spec) describeSchemaLocation(location string) string {
+	parts := strings.Split(strings.TrimPrefix(location, "/"), "/")
+	if len(parts) < 2 || parts[0] != "remotes" {
+		return "spec"
+	}
+	remoteIndex, err := strconv.Atoi(parts[1])
+	if err != nil || remoteIndex < 0 || remoteIndex >= len(spec.Remotes) {
+		return "spec"
+	}
+
+	field := ""
+	if len(parts) > 2 {
+		field = parts[2]
+	}
+	return spec.describeRemote(remoteIndex, field)
+}
@@ -0,0 +1,132 @@
+package vdmspec
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchema(t *testing.T) {
+	schema, err := Schema()
+	require.NoError(t, err)
+
+	t.Run("matches the published schema", func(t *testing.T) {
+		published, err := os.ReadFile(filepath.Join("..", "..", "vdm.schema.json"))
+		require.NoError(t, err)
+		assert.Equal(t, string(published), string(schema), "vdm.schema.json is out of date -- regenerate it with 'go run . schema > vdm.schema.json'")
+	})
+
+	t.Run("documents every remote field", func(t *testing.T) {
+		var parsed struct {
+			Defs struct {
+				Remote struct {
+					Properties map[string]struct {
+						Description string `json:"description"`
+					} `json:"properties"`
+					Required []string `json:"required"`
+				} `json:"remote"`
+			} `json:"$defs"`
+		}
+		require.NoError(t, json.Unmarshal(schema, &parsed))
+
+		assert.Len(t, parsed.Defs.Remote.Properties, len(schemaFields)-1)
+		for name, property := range parsed.Defs.Remote.Properties {
+			assert.NotEmpty(t, property.Description, name)
+		}
+		assert.Equal(t, []string{"remote", "local_path"}, parsed.Defs.Remote.Required)
+	})
+
+	t.Run("passes every spec that passes the field checks", func(t *testing.T) {
+		// Every combination of values that each field's checks care about
+		var remotes []Remote
+		for _, remoteType := range []string{"", GitType, FileType} {
+			for _, version := range []string{"", "v1.0.0", "^1.2"} {
+				for _, subdir := range []string{"", "proto"} {
+					for _, archive := range []string{"", ArchiveNone, ArchiveZip} {
+						for _, stripComponents := range []int{0, 1} {
+							for _, sha256 := range []string{"", strings.Repeat("a", 64)} {
+								for _, flags := range []struct{ keepGitDir, prerelease, filtered bool }{
+									{}, {keepGitDir: true}, {prerelease: true}, {filtered: true},
+								} {
+									remote := Remote{
+										Type:            remoteType,
+										Remote:          "https://some-remote",
+										Version:         version,
+										LocalPath:       "./deps/some-remote",
+										Subdir:          subdir,
+										Archive:         archive,
+										StripComponents: stripComponents,
+										SHA256:          sha256,
+										KeepGitDir:      flags.keepGitDir,
+										Prerelease:      flags.prerelease,
+									}
+									if flags.filtered {
+										remote.Include = []string{"*.proto"}
+									}
+									remotes = append(remotes, remote)
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+
+		passed := 0
+		for _, remote := range remotes {
+			spec := Spec{Remotes: []Remote{remote}}
+			if len(spec.validateFields()) > 0 {
+				continue
+			}
+			passed++
+			assert.Empty(t, spec.validateSchema(), "%+v", remote)
+		}
+		assert.NotZero(t, passed)
+	})
+
+	t.Run("passes valid specs", func(t *testing.T) {
+		spec := Spec{Remotes: []Remote{
+			{Name: "go-common", Remote: "https://some-remote", Version: "^1.2", Prerelease: true, LocalPath: "./deps/go-common", Tags: []string{"go"}},
+			{Type: FileType, Remote: "https://some-remote/some.tar.gz", LocalPath: "./deps/some", Archive: ArchiveTarGz, StripComponents: 1},
+		}}
+		assert.Empty(t, spec.validateSchema())
+		assert.Empty(t, Spec{}.validateSchema())
+
+		untyped := Spec{Remotes: []Remote{{Remote: "https://github.com/opensourcecorp/go-common", LocalPath: "./deps/go-common"}}}
+		assert.Empty(t, untyped.validateSchema())
+		assert.NoError(t, untyped.Validate())
+	})
+
+	t.Run("fails on git remote without version", func(t *testing.T) {
+		spec := Spec{Remotes: []Remote{{Type: GitType, Remote: "https://some-remote", LocalPath: "./deps/some-remote"}}}
+		failures := spec.validateSchema()
+		require.Len(t, failures, 1)
+		assert.Contains(t, failures[0].Error(), "remote #0 'some-remote'")
+		assert.Contains(t, failures[0].Error(), "version")
+	})
+
+	t.Run("fails on file-only fields for git remote", func(t *testing.T) {
+		spec := Spec{Remotes: []Remote{{Remote: "https://some-remote", Version: "v1", LocalPath: "./deps/some-remote", Archive: ArchiveZip}}}
+		failures := spec.validateSchema()
+		require.Len(t, failures, 1)
+		assert.True(t, strings.HasPrefix(failures[0].Error(), "remote #0 'some-remote': "), failures[0].Error())
+	})
+
+	t.Run("fails on git-only fields for file remote", func(t *testing.T) {
+		spec := Spec{Remotes: []Remote{{Type: FileType, Remote: "https://some-remote/some.file", LocalPath: "./deps/some.file", Subdir: "some-dir"}}}
+		assert.Len(t, spec.validateSchema(), 1)
+	})
+
+	t.Run("reports failures by line for specs read from specfiles", func(t *testing.T) {
+		spec, err := parseSpec("vdm.yaml", []byte("remotes:\n  - type: git\n    remote: https://some-remote\n    local_path: ./deps/some-remote\n"))
+		require.NoError(t, err)
+		failures := spec.validateSchema()
+		require.Len(t, failures, 1)
+		assert.True(t, strings.HasPrefix(failures[0].Error(), "vdm.yaml:2:5: remote 'some-remote': "), failures[0].Error())
+	})
+}
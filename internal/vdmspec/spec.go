@@ -1,10 +1,14 @@
 package vdmspec
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
 	"strings"
 
 	"github.com/opensourcecorp/vdm/internal/message"
@@ -14,15 +18,140 @@ import (
 // Spec defines the overall structure of the vmd specfile.
 type Spec struct {
 	Remotes []Remote `json:"remotes" yaml:"remotes"`
+	// source is where the spec was read from, if it was read from a specfile,
+	// so that validation failures can point at the offending lines.
+	source *specSource
+}
+
+// specSource records the specfile that a [Spec] was read from, along with the
+// YAML node of each of its remotes.
+type specSource struct {
+	path    string
+	remotes []*yaml.Node
 }
 
 // Remote defines the structure of each remote configuration in the vdm
 // specfile.
 type Remote struct {
+	// Name is a unique identifier for the remote, to refer to it by in logs and
+	// on the command line. If it's empty, one is derived from the local path
+	// (see [Remote.DisplayName]).
+	Name      string `json:"name,omitempty" yaml:"name,omitempty"`
 	Type      string `json:"type,omitempty" yaml:"type,omitempty"`
 	Remote    string `json:"remote" yaml:"remote"`
 	Version   string `json:"version,omitempty" yaml:"version,omitempty"`
 	LocalPath string `json:"local_path" yaml:"local_path"`
+	// KeepGitDir controls whether the .git directory is left in place for git
+	// remotes, so that the local copy can be worked on like any other clone.
+	KeepGitDir bool `json:"keep_git_dir,omitempty" yaml:"keep_git_dir,omitempty"`
+	// Subdir is the directory within a git remote to retrieve, instead of the
+	// whole repository. Its contents are placed at the local path.
+	Subdir string `json:"subdir,omitempty" yaml:"subdir,omitempty"`
+	// SHA256 and SHA512 pin the expected digests of a file remote's contents,
+	// as hex strings. A retrieved file that doesn't match is rejected.
+	SHA256 string `json:"sha256,omitempty" yaml:"sha256,omitempty"`
+	SHA512 string `json:"sha512,omitempty" yaml:"sha512,omitempty"`
+	// Archive is the format of a file remote that's an archive to be unpacked
+	// into the local path. If empty, it's detected from the remote's file
+	// extension or the server's content type. Set it to [ArchiveNone] to keep
+	// an archive as-is.
+	Archive string `json:"archive,omitempty" yaml:"archive,omitempty"`
+	// StripComponents is the number of leading path components to remove from
+	// each entry when unpacking an archive, like tar's --strip-components.
+	StripComponents int `json:"strip_components,omitempty" yaml:"strip_components,omitempty"`
+	// Include and Exclude are glob patterns (which support '**') for paths
+	// relative to the local path, to filter what's kept from a git remote or
+	// unpacked archive. If Include is set, only matching files are kept, and
+	// then any files matching Exclude are removed. A pattern that matches a
+	// directory applies to everything in it.
+	Include []string `json:"include,omitempty" yaml:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty" yaml:"exclude,omitempty"`
+	// Prerelease allows pre-release tags (like 'v2.0.0-rc.1') to satisfy a git
+	// remote's version constraint, even if the constraint doesn't name a
+	// pre-release itself.
+	Prerelease bool `json:"prerelease,omitempty" yaml:"prerelease,omitempty"`
+	// AllowOutsideProject allows the local path to be absolute, or to be outside
+	// of the project root (i.e. the directory vdm is run from). Without it, vdm
+	// refuses to write anywhere else.
+	AllowOutsideProject bool `json:"allow_outside_project,omitempty" yaml:"allow_outside_project,omitempty"`
+	// Tags are arbitrary labels for the remote, so that groups of remotes can be
+	// selected on the command line with --tag.
+	Tags []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+}
+
+// Equal reports whether the two remotes are specified identically. The remotes'
+// names and tags aren't considered, since they don't change what's retrieved.
+// Neither is KeepGitDir, so that turning it off (e.g. by dropping 'vdm sync
+// --keep-git-dir') never replaces a kept clone, and any local commits in it;
+// turning it on is planned as an update by itself.
+func (r Remote) Equal(other Remote) bool {
+	for _, remote := range []*Remote{&r, &other} {
+		remote.Name = ""
+		remote.Tags = nil
+		remote.KeepGitDir = false
+		// Missing & empty filter lists mean the same thing
+		if len(remote.Include) == 0 {
+			remote.Include = nil
+		}
+		if len(remote.Exclude) == 0 {
+			remote.Exclude = nil
+		}
+	}
+
+	return reflect.DeepEqual(r, other)
+}
+
+// IsZero reports whether the remote is entirely unset.
+func (r Remote) IsZero() bool {
+	return r.Equal(Remote{}) && r.Name == "" && len(r.Tags) == 0
+}
+
+// HasTag reports whether the remote has the provided tag.
+func (r Remote) HasTag(tag string) bool {
+	for _, remoteTag := range r.Tags {
+		if remoteTag == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// HasFilters reports whether the remote has any include or exclude patterns.
+func (r Remote) HasFilters() bool {
+	return len(r.Include) > 0 || len(r.Exclude) > 0
+}
+
+// VDMMeta defines the structure of the metafile that vdm writes to disk for
+// each remote it has synced. It records the remote's spec as it was at sync
+// time, along with the [Manifest] of what was placed on disk.
+type VDMMeta struct {
+	Remote   `yaml:",inline"`
+	Git      *GitMeta  `json:"git,omitempty" yaml:"git,omitempty"`
+	File     *FileMeta `json:"file,omitempty" yaml:"file,omitempty"`
+	Manifest Manifest  `json:"manifest,omitempty" yaml:"manifest,omitempty"`
+}
+
+// GitMeta records what a git remote's version resolved to when it was last
+// synced.
+type GitMeta struct {
+	Commit string `json:"commit" yaml:"commit"`
+	// Tag is the tag that a version constraint resolved to, if the remote's
+	// version is one.
+	Tag string `json:"tag,omitempty" yaml:"tag,omitempty"`
+}
+
+// FileMeta records what the server said about a file remote's contents when it
+// was last retrieved, so that later syncs can cheaply ask the server whether
+// they've changed since.
+type FileMeta struct {
+	ETag         string `json:"etag,omitempty" yaml:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty" yaml:"last_modified,omitempty"`
+	SHA256       string `json:"sha256" yaml:"sha256"`
+}
+
+// IsZero reports whether the metafile was actually found on disk.
+func (m VDMMeta) IsZero() bool {
+	return m.Remote.IsZero() && m.Git == nil && m.File == nil && len(m.Manifest) == 0
 }
 
 const (
@@ -36,6 +165,18 @@ const (
 	FileType string = "file"
 )
 
+// Archive formats that file remotes can be unpacked from.
+const (
+	// ArchiveNone keeps a file remote as-is, even if it looks like an archive.
+	ArchiveNone   string = "none"
+	ArchiveTar    string = "tar"
+	ArchiveTarGz  string = "tar.gz"
+	ArchiveTarXz  string = "tar.xz"
+	ArchiveTarBz2 string = "tar.bz2"
+	ArchiveTarZst string = "tar.zst"
+	ArchiveZip    string = "zip"
+)
+
 // MakeMetaFilePath constructs the metafile path that vdm will use to track a
 // remote's state on disk.
 func (r Remote) MakeMetaFilePath() string {
@@ -51,11 +192,26 @@ func (r Remote) MakeMetaFilePath() string {
 	return metaFilePath
 }
 
-// WriteVDMMeta writes the metafile contents to disk, the path of which is
-// determined by [Remote.MakeMetaFilePath].
+// WriteVDMMeta writes the metafile contents for the remote to disk. See
+// [VDMMeta.Write].
 func (r Remote) WriteVDMMeta() error {
-	metaFilePath := r.MakeMetaFilePath()
-	vdmMetaContent, err := yaml.Marshal(r)
+	return VDMMeta{Remote: r}.Write()
+}
+
+// Write writes the metafile contents to disk, the path of which is determined
+// by [Remote.MakeMetaFilePath]. The manifest of the remote's content is built
+// from what's currently on disk, so this should be called after the remote has
+// been synced.
+func (m VDMMeta) Write() error {
+	metaFilePath := m.MakeMetaFilePath()
+
+	manifest, err := m.BuildManifest()
+	if err != nil {
+		return fmt.Errorf("building manifest for %s: %w", metaFilePath, err)
+	}
+	m.Manifest = manifest
+
+	vdmMetaContent, err := yaml.Marshal(m)
 	if err != nil {
 		return fmt.Errorf("writing %s: %w", metaFilePath, err)
 	}
@@ -73,27 +229,27 @@ func (r Remote) WriteVDMMeta() error {
 
 // GetVDMMeta reads the metafile from disk, and returns it for further
 // processing.
-func (r Remote) GetVDMMeta() (Remote, error) {
+func (r Remote) GetVDMMeta() (VDMMeta, error) {
 	metaFilePath := r.MakeMetaFilePath()
 	_, err := os.Stat(metaFilePath)
 	if errors.Is(err, os.ErrNotExist) {
-		return Remote{}, nil // this is ok, because it might literally not exist yet
+		return VDMMeta{}, nil // this is ok, because it might literally not exist yet
 	} else if err != nil {
-		return Remote{}, fmt.Errorf("couldn't check if %s exists at '%s': %w", MetaFileName, metaFilePath, err)
+		return VDMMeta{}, fmt.Errorf("couldn't check if %s exists at '%s': %w", MetaFileName, metaFilePath, err)
 	}
 
 	vdmMetaFile, err := os.ReadFile(metaFilePath)
 	if err != nil {
 		message.Debugf("error reading VMDMMETA from disk: %w", err)
-		return Remote{}, fmt.Errorf("there was a problem reading the %s file from '%s': %w", MetaFileName, metaFilePath, err)
+		return VDMMeta{}, fmt.Errorf("there was a problem reading the %s file from '%s': %w", MetaFileName, metaFilePath, err)
 	}
 	message.Debugf("%s contents read:\n%s", MetaFileName, string(vdmMetaFile))
 
-	var vdmMeta Remote
+	var vdmMeta VDMMeta
 	err = yaml.Unmarshal(vdmMetaFile, &vdmMeta)
 	if err != nil {
 		message.Debugf("error during %s unmarshal: w", MetaFileName, err)
-		return Remote{}, fmt.Errorf("there was a problem reading the contents of the %s file at '%s': %w", MetaFileName, metaFilePath, err)
+		return VDMMeta{}, fmt.Errorf("there was a problem reading the contents of the %s file at '%s': %w", MetaFileName, metaFilePath, err)
 	}
 	message.Debugf("file %s unmarshalled: %+v", MetaFileName, vdmMeta)
 
@@ -120,22 +276,130 @@ func GetSpecFromFile(specFilePath string) (Spec, error) {
 	}
 	message.Debugf("specfile contents read:\n%s", string(specFile))
 
+	return parseSpec(specFilePath, specFile)
+}
+
+// unknownFieldRegex matches the errors that yaml.v3 returns for fields that
+// aren't in the type being decoded into.
+var unknownFieldRegex = regexp.MustCompile(`^line (\d+): field (\S+) not found in type \S+$`)
+
+// parseSpec unmarshals the contents of a specfile, rejecting any fields that
+// aren't part of the spec (which are most likely typos).
+func parseSpec(specFilePath string, specFile []byte) (Spec, error) {
 	var spec Spec
-	err = yaml.Unmarshal(specFile, &spec)
-	if err != nil {
-		message.Debugf("error during specfile unmarshal: w", err)
-		return Spec{}, fmt.Errorf("there was a problem reading the contents of your vdm spec file: %w", err)
+	decoder := yaml.NewDecoder(bytes.NewReader(specFile))
+	decoder.KnownFields(true)
+	err := decoder.Decode(&spec)
+
+	var typeErr *yaml.TypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		message.Debugf("specfile '%s' is empty", specFilePath)
+	case errors.As(err, &typeErr):
+		failures := make([]string, 0, len(typeErr.Errors))
+		for _, failure := range typeErr.Errors {
+			if match := unknownFieldRegex.FindStringSubmatch(failure); match != nil {
+				failure = fmt.Sprintf("line %s: unknown field '%s'%s", match[1], match[2], suggestField(match[2]))
+			}
+			failures = append(failures, fmt.Sprintf("%s:%s", specFilePath, strings.TrimPrefix(failure, "line ")))
+		}
+		return Spec{}, fmt.Errorf("there was a problem reading the contents of your vdm spec file:\n  %s", strings.Join(failures, "\n  "))
+	case err != nil:
+		message.Debugf("error during specfile unmarshal: %v", err)
+		return Spec{}, fmt.Errorf("there was a problem reading the contents of your vdm spec file '%s': %w", specFilePath, err)
 	}
 	message.Debugf("vdmSpecs unmarshalled: %+v", spec)
 
+	spec.source = &specSource{path: specFilePath}
+	var root yaml.Node
+	if err := yaml.Unmarshal(specFile, &root); err == nil && len(root.Content) > 0 {
+		if remotesNode := mappingValue(root.Content[0], "remotes"); remotesNode != nil {
+			spec.source.remotes = remotesNode.Content
+		}
+	}
+
 	return spec, nil
 }
 
+// suggestField returns a suggestion of the spec field that an unknown field was
+// probably meant to be, or nothing if none are close enough.
+func suggestField(unknown string) string {
+	best, bestDistance := "", 3
+	for _, specType := range []reflect.Type{reflect.TypeOf(Spec{}), reflect.TypeOf(Remote{})} {
+		for i := 0; i < specType.NumField(); i++ {
+			field, _, _ := strings.Cut(specType.Field(i).Tag.Get("yaml"), ",")
+			if field == "" {
+				continue
+			}
+			if distance := editDistance(unknown, field); distance < bestDistance {
+				best, bestDistance = field, distance
+			}
+		}
+	}
+
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean '%s'?)", best)
+}
+
+// editDistance returns the Levenshtein distance between the two strings.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = current[j-1] + 1
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
+			}
+			if previous[j-1]+cost < current[j] {
+				current[j] = previous[j-1] + cost
+			}
+		}
+		previous = current
+	}
+
+	return previous[len(b)]
+}
+
+// nameRegex matches the names that remotes can be given, which are also what
+// names derived from local paths are slugified to.
+var nameRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// nameInvalidCharsRegex matches runs of characters that can't be in a name.
+var nameInvalidCharsRegex = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// DisplayName returns a short, human-friendly name for the remote. That's its
+// name if it has one, and otherwise the last element of its local path, as a
+// slug.
+func (r Remote) DisplayName() string {
+	if r.Name != "" {
+		return r.Name
+	}
+
+	slug := nameInvalidCharsRegex.ReplaceAllString(filepath.Base(r.LocalPath), "-")
+	return strings.TrimRight(strings.TrimLeft(slug, ".-_"), "-")
+}
+
 // OpMsg constructs a loggable message outlining the specific operation being
 // performed at the moment
 func (r Remote) OpMsg() string {
+	msg := fmt.Sprintf("%s --> %s", r.Remote, r.LocalPath)
 	if r.Version != "" {
-		return fmt.Sprintf("%s@%s --> %s", r.Remote, r.Version, r.LocalPath)
+		msg = fmt.Sprintf("%s@%s --> %s", r.Remote, r.Version, r.LocalPath)
+	}
+	if r.Name != "" {
+		return fmt.Sprintf("%s (%s)", r.Name, msg)
 	}
-	return fmt.Sprintf("%s --> %s", r.Remote, r.LocalPath)
+	return msg
 }
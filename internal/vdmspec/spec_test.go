@@ -41,7 +41,13 @@ func TestVDMMeta(t *testing.T) {
 
 		got, err := testRemote.GetVDMMeta()
 		require.NoError(t, err)
-		assert.Equal(t, testRemote, got)
+		assert.Equal(t, testRemote, got.Remote)
+	})
+
+	t.Run("GetVDMMeta when missing", func(t *testing.T) {
+		got, err := testRemote.GetVDMMeta()
+		require.NoError(t, err)
+		assert.True(t, got.IsZero())
 	})
 
 	t.Run("WriteVDMMeta", func(t *testing.T) {
@@ -59,7 +65,9 @@ func TestVDMMeta(t *testing.T) {
 
 		got, err := testRemote.GetVDMMeta()
 		require.NoError(t, err)
-		assert.Equal(t, testRemote, got)
+		assert.Equal(t, testRemote, got.Remote)
+		assert.Contains(t, got.Manifest, "vdm.yaml")
+		assert.NotContains(t, got.Manifest, MetaFileName)
 	})
 
 	t.Run("GetSpecsFromFile", func(t *testing.T) {
@@ -73,3 +81,94 @@ func TestVDMMeta(t *testing.T) {
 		assert.Equal(t, 5, len(spec.Remotes))
 	})
 }
+
+func TestRemoteEqual(t *testing.T) {
+	remote := Remote{Remote: "https://some-remote", Version: "v1.0.0", LocalPath: "./deps/some-remote"}
+
+	t.Run("missing & empty filters are equal", func(t *testing.T) {
+		other := remote
+		other.Exclude = []string{}
+		assert.True(t, remote.Equal(other))
+	})
+
+	t.Run("different filters are not equal", func(t *testing.T) {
+		other := remote
+		other.Exclude = []string{"docs"}
+		assert.False(t, remote.Equal(other))
+	})
+
+	t.Run("names & tags are ignored", func(t *testing.T) {
+		other := remote
+		other.Name = "some-name"
+		other.Tags = []string{"some-tag"}
+		assert.True(t, remote.Equal(other))
+	})
+
+	t.Run("keep_git_dir is ignored", func(t *testing.T) {
+		other := remote
+		other.KeepGitDir = true
+		assert.True(t, remote.Equal(other))
+	})
+
+	t.Run("IsZero", func(t *testing.T) {
+		assert.True(t, Remote{}.IsZero())
+		assert.False(t, remote.IsZero())
+		assert.False(t, Remote{Name: "some-name"}.IsZero())
+	})
+}
+
+func TestRemoteDisplayName(t *testing.T) {
+	for _, tc := range []struct {
+		remote Remote
+		want   string
+	}{
+		{remote: Remote{LocalPath: "./deps/go-common"}, want: "go-common"},
+		{remote: Remote{LocalPath: "./deps/proto/http.proto"}, want: "http.proto"},
+		{remote: Remote{LocalPath: "./deps/some lib (v2)"}, want: "some-lib-v2"},
+		{remote: Remote{LocalPath: "./deps/.hidden"}, want: "hidden"},
+		{remote: Remote{Name: "common", LocalPath: "./deps/go-common"}, want: "common"},
+	} {
+		assert.Equal(t, tc.want, tc.remote.DisplayName(), tc.remote.LocalPath)
+	}
+}
+
+func TestRemoteOpMsg(t *testing.T) {
+	remote := Remote{Remote: "https://some-remote", Version: "v1.0.0", LocalPath: "./deps/some-remote"}
+	assert.Equal(t, "https://some-remote@v1.0.0 --> ./deps/some-remote", remote.OpMsg())
+
+	remote.Name = "some-name"
+	assert.Equal(t, "some-name (https://some-remote@v1.0.0 --> ./deps/some-remote)", remote.OpMsg())
+}
+
+func TestParseSpec(t *testing.T) {
+	t.Run("rejects unknown fields", func(t *testing.T) {
+		_, err := parseSpec("vdm.yaml", []byte("remotes:\n  - remote: https://some-remote\n    version: v1\n    loacl_path: ./deps/some-remote\n"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "vdm.yaml:4: unknown field 'loacl_path' (did you mean 'local_path'?)")
+	})
+
+	t.Run("rejects unknown fields in JSON", func(t *testing.T) {
+		_, err := parseSpec("vdm.json", []byte(`{"remotes": [{"remote": "https://some-remote", "sha": "abc"}]}`))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "vdm.json:1: unknown field 'sha'")
+	})
+
+	t.Run("empty spec file", func(t *testing.T) {
+		spec, err := parseSpec("vdm.yaml", nil)
+		require.NoError(t, err)
+		assert.Empty(t, spec.Remotes)
+	})
+
+	t.Run("records where remotes are", func(t *testing.T) {
+		spec, err := parseSpec("vdm.yaml", []byte("remotes:\n  - remote: https://some-remote\n    local_path: ./deps/some-remote\n"))
+		require.NoError(t, err)
+		assert.Equal(t, "vdm.yaml:3:17: remote 'some-remote'", spec.describeRemote(0, "local_path"))
+		assert.Equal(t, "vdm.yaml:2:5: remote 'some-remote'", spec.describeRemote(0, "version"))
+	})
+}
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("version", "version"))
+	assert.Equal(t, 2, editDistance("verison", "version"))
+	assert.Equal(t, 3, editDistance("", "sha"))
+}
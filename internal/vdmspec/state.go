@@ -0,0 +1,145 @@
+package vdmspec
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/opensourcecorp/vdm/internal/message"
+	"gopkg.in/yaml.v3"
+)
+
+// StateFileName is the name of the file, kept alongside the specfile, that vdm
+// uses to track every remote it has ever placed on disk. This is what lets vdm
+// clean up after remotes that have since been removed from the specfile.
+const StateFileName string = ".vdmstate"
+
+// State defines the structure of the vdm state file.
+type State struct {
+	Remotes []Remote `json:"remotes" yaml:"remotes"`
+}
+
+// StateFilePath returns the path of the state file that belongs to the
+// specfile at the provided path.
+func StateFilePath(specFilePath string) string {
+	return filepath.Join(filepath.Dir(specFilePath), StateFileName)
+}
+
+// GetStateFromFile reads the state file from disk. A state file that doesn't
+// exist yet is not an error, and yields an empty [State].
+func GetStateFromFile(stateFilePath string) (State, error) {
+	stateFile, err := os.ReadFile(stateFilePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return State{}, nil
+	} else if err != nil {
+		return State{}, fmt.Errorf("there was a problem reading the %s file from '%s': %w", StateFileName, stateFilePath, err)
+	}
+	message.Debugf("%s contents read:\n%s", StateFileName, string(stateFile))
+
+	var state State
+	err = yaml.Unmarshal(stateFile, &state)
+	if err != nil {
+		return State{}, fmt.Errorf("there was a problem reading the contents of the %s file at '%s': %w", StateFileName, stateFilePath, err)
+	}
+
+	return state, nil
+}
+
+// WriteToFile writes the state file to disk.
+func (s State) WriteToFile(stateFilePath string) error {
+	stateContent, err := yaml.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("marshalling %s: %w", StateFileName, err)
+	}
+
+	message.Debugf("writing state file to '%s'", stateFilePath)
+	err = os.WriteFile(stateFilePath, stateContent, 0644)
+	if err != nil {
+		return fmt.Errorf("writing state file: %w", err)
+	}
+
+	return nil
+}
+
+// Track adds the provided remotes to the state, replacing any existing entries
+// that share the same local path.
+func (s State) Track(remotes ...Remote) State {
+	tracked := State{Remotes: make([]Remote, 0, len(s.Remotes)+len(remotes))}
+	for _, existing := range s.Remotes {
+		replaced := false
+		for _, remote := range remotes {
+			if filepath.Clean(existing.LocalPath) == filepath.Clean(remote.LocalPath) {
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			tracked.Remotes = append(tracked.Remotes, existing)
+		}
+	}
+	tracked.Remotes = append(tracked.Remotes, remotes...)
+
+	return tracked
+}
+
+// Orphans returns every remote in the state that no longer has a corresponding
+// local path in the provided spec.
+func (s State) Orphans(spec Spec) []Remote {
+	var orphans []Remote
+	for _, tracked := range s.Remotes {
+		found := false
+		for _, remote := range spec.Remotes {
+			if filepath.Clean(tracked.LocalPath) == filepath.Clean(remote.LocalPath) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			orphans = append(orphans, tracked)
+		}
+	}
+
+	return orphans
+}
+
+// PathsOverlap reports whether the two paths are the same, or if either one is
+// nested inside the other.
+func PathsOverlap(a, b string) bool {
+	a, b = filepath.Clean(a), filepath.Clean(b)
+	if a == b {
+		return true
+	}
+
+	rel, err := filepath.Rel(a, b)
+	if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return true
+	}
+	rel, err = filepath.Rel(b, a)
+	if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return true
+	}
+
+	return false
+}
+
+// Untrack removes the provided remotes from the state, matching on their local
+// paths.
+func (s State) Untrack(remotes ...Remote) State {
+	var remaining State
+	for _, tracked := range s.Remotes {
+		untracked := false
+		for _, remote := range remotes {
+			if filepath.Clean(tracked.LocalPath) == filepath.Clean(remote.LocalPath) {
+				untracked = true
+				break
+			}
+		}
+		if !untracked {
+			remaining.Remotes = append(remaining.Remotes, tracked)
+		}
+	}
+
+	return remaining
+}
@@ -0,0 +1,58 @@
+package vdmspec
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestState(t *testing.T) {
+	remoteA := Remote{Remote: "https://some-remote/a", Version: "v1.0.0", LocalPath: "./deps/a"}
+	remoteB := Remote{Remote: "https://some-remote/b", Version: "v1.0.0", LocalPath: "./deps/b"}
+
+	t.Run("round-trips through disk", func(t *testing.T) {
+		stateFilePath := StateFilePath(filepath.Join(t.TempDir(), "vdm.yaml"))
+
+		state, err := GetStateFromFile(stateFilePath)
+		require.NoError(t, err)
+		assert.Empty(t, state.Remotes)
+
+		state = State{Remotes: []Remote{remoteA, remoteB}}
+		require.NoError(t, state.WriteToFile(stateFilePath))
+
+		got, err := GetStateFromFile(stateFilePath)
+		require.NoError(t, err)
+		assert.Equal(t, state, got)
+	})
+
+	t.Run("Track replaces remotes with the same local path", func(t *testing.T) {
+		changedA := remoteA
+		changedA.Version = "v2.0.0"
+		changedA.LocalPath = "deps/a"
+
+		state := State{Remotes: []Remote{remoteA, remoteB}}.Track(changedA)
+		assert.Equal(t, []Remote{remoteB, changedA}, state.Remotes)
+	})
+
+	t.Run("Untrack", func(t *testing.T) {
+		state := State{Remotes: []Remote{remoteA, remoteB}}.Untrack(Remote{LocalPath: "deps/a"})
+		assert.Equal(t, []Remote{remoteB}, state.Remotes)
+	})
+
+	t.Run("Orphans", func(t *testing.T) {
+		state := State{Remotes: []Remote{remoteA, remoteB}}
+		spec := Spec{Remotes: []Remote{remoteB}}
+		assert.Equal(t, []Remote{remoteA}, state.Orphans(spec))
+	})
+
+	t.Run("PathsOverlap", func(t *testing.T) {
+		assert.True(t, PathsOverlap("./deps/a", "deps/a"))
+		assert.True(t, PathsOverlap("./deps", "./deps/a"))
+		assert.True(t, PathsOverlap("./deps/a/b", "./deps/a"))
+		assert.False(t, PathsOverlap("./deps/a", "./deps/b"))
+		assert.False(t, PathsOverlap("./deps/a", "./deps/ab"))
+		assert.True(t, PathsOverlap("./..foo", "."))
+	})
+}
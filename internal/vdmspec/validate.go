@@ -3,43 +3,196 @@ package vdmspec
 import (
 	"errors"
 	"fmt"
+	"os"
+	"path"
+	"path/filepath"
 	"regexp"
+	"strings"
 
+	"github.com/bmatcuk/doublestar/v4"
 	"github.com/opensourcecorp/vdm/internal/message"
 )
 
+// hexDigestRegex matches the hex-encoded digests that remotes can be pinned to.
+var hexDigestRegex = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+
+// protocolRegex matches the protocol specifiers (or other prefixes) that
+// remotes must have.
+var protocolRegex = regexp.MustCompile(`(http(s?)://|git://|git@)`)
+
 // Validate performs runtime validations on the vdm specfile, and informs the
-// caller of any failures encountered.
+// caller of any failures encountered. Each failure names the remote it's for,
+// and if the spec was read from a specfile, the file, line, and column of the
+// offending field.
 func (spec Spec) Validate() error {
+	allErrors := spec.validateFields()
+
+	// The schema is only checked once everything else passes, so that failures
+	// get the more helpful messages from [Spec.validateFields]. Anything it
+	// catches after that means that the two have drifted apart.
+	if len(allErrors) == 0 {
+		allErrors = spec.validateSchema()
+	}
+
+	if len(allErrors) > 0 {
+		for _, err := range allErrors {
+			message.Errorf("validation failure: %s", err.Error())
+		}
+		return fmt.Errorf("%d validation failure(s) found in your vdm spec file", len(allErrors))
+	}
+	return nil
+}
+
+// validateFields checks each remote's fields, and returns every failure.
+func (spec Spec) validateFields() []error {
 	var allErrors []error
 
+	names := make(map[string]int)
 	for remoteIndex, remote := range spec.Remotes {
+		// fail records a failure for the provided field of this remote
+		fail := func(field string, format string, args ...any) {
+			allErrors = append(allErrors, fmt.Errorf("%s: %s", spec.describeRemote(remoteIndex, field), fmt.Sprintf(format, args...)))
+		}
+
+		// Name & Tags fields
+		message.Debugf("Index #%d: validating fields 'Name' & 'Tags' for %+v", remoteIndex, remote)
+		if remote.Name != "" && !nameRegex.MatchString(remote.Name) {
+			fail("name", "'name' provided as '%s', but it must start with a letter or number, and only contain letters, numbers, '.', '-', and '_'", remote.Name)
+		}
+		// Only names that were set have to be unique, since derived names can
+		// clash without anything being wrong (see [Remote.DisplayName])
+		if remote.Name != "" {
+			if otherIndex, ok := names[remote.Name]; ok {
+				fail("name", "named '%s', the same as %s, but names must be unique", remote.Name, spec.describeRemote(otherIndex, "name"))
+			} else {
+				names[remote.Name] = remoteIndex
+			}
+		}
+		for _, tag := range remote.Tags {
+			if strings.TrimSpace(tag) == "" {
+				fail("tags", "'tags' can't have an empty tag")
+			}
+		}
+
 		// Remote field
 		message.Debugf("Index #%d: validating field 'Remote' for %+v", remoteIndex, remote)
 		if len(remote.Remote) == 0 {
-			allErrors = append(allErrors, errors.New("all 'remote' fields must be non-zero length"))
-		}
-		protocolRegex := regexp.MustCompile(`(http(s?)://|git://|git@)`)
-		if !protocolRegex.MatchString(remote.Remote) {
-			allErrors = append(
-				allErrors,
-				fmt.Errorf("remote #%d provided as '%s', but all 'remote' fields must begin with a protocol specifier or other valid prefix (e.g. 'https://', '(user|git)@', etc.)", remoteIndex, remote.Remote),
-			)
+			fail("remote", "'remote' must be non-zero length")
+		} else if !protocolRegex.MatchString(remote.Remote) {
+			fail("remote", "'remote' provided as '%s', but it must begin with a protocol specifier or other valid prefix (e.g. 'https://', '(user|git)@', etc.)", remote.Remote)
 		}
 
 		// Version field
 		message.Debugf("Index #%d: validating field 'Version' for %+v", remoteIndex, remote)
 		if remote.Type == GitType && len(remote.Version) == 0 {
-			allErrors = append(allErrors, errors.New("all 'version' fields for the 'git' remote type must be non-zero length. If you don't care about the version (even though you probably should), then use 'latest'"))
+			fail("version", "'version' must be non-zero length for the '%s' remote type. If you don't care about the version (even though you probably should), then use 'latest'", GitType)
 		}
 		if remote.Type == FileType && len(remote.Version) > 0 {
-			message.Warnf("NOTE: Remote #%d '%s' specified as type '%s', which does not take explicit version info (you provided '%s'); ignoring version field", remoteIndex, remote.Remote, remote.Type, remote.Version)
+			message.Warnf("NOTE: %s: specified as type '%s', which does not take explicit version info (you provided '%s'); ignoring version field", spec.describeRemote(remoteIndex, "version"), remote.Type, remote.Version)
+		}
+
+		if remote.IsVersionConstraint() {
+			if remote.Type != GitType && remote.Type != "" {
+				fail("version", "has version constraint '%s', but only the '%s' remote type can resolve one", remote.Version, GitType)
+			}
+			if _, err := remote.VersionConstraint(); err != nil {
+				fail("version", "has an invalid version constraint: %s", err)
+			}
+		} else if remote.Prerelease {
+			fail("prerelease", "has 'prerelease' set, but its version '%s' isn't a version constraint", remote.Version)
 		}
 
-		// LocalPath field
-		message.Debugf("Index #%d: validating field 'LocalPath' for %+v", remoteIndex, remote)
+		// LocalPath & AllowOutsideProject fields
+		message.Debugf("Index #%d: validating fields 'LocalPath' & 'AllowOutsideProject' for %+v", remoteIndex, remote)
 		if len(remote.LocalPath) == 0 {
-			allErrors = append(allErrors, errors.New("all 'local_path' fields must be non-zero length"))
+			fail("local_path", "'local_path' must be non-zero length")
+		} else if err := validateLocalPath(remote); err != nil {
+			fail("local_path", "'local_path' provided as '%s', but %s", remote.LocalPath, err)
+		}
+		for otherIndex, other := range spec.Remotes[:remoteIndex] {
+			if other.LocalPath != "" && remote.LocalPath != "" && PathsOverlap(other.LocalPath, remote.LocalPath) {
+				fail("local_path", "'local_path' provided as '%s' overlaps with '%s' of %s, but they'd clobber each other when synced", remote.LocalPath, other.LocalPath, spec.describeRemote(otherIndex, "local_path"))
+			}
+		}
+
+		// Subdir field
+		message.Debugf("Index #%d: validating field 'Subdir' for %+v", remoteIndex, remote)
+		if remote.Subdir != "" {
+			if remote.Type != GitType && remote.Type != "" {
+				fail("subdir", "has a 'subdir' field, but only the '%s' remote type can retrieve a subdirectory", GitType)
+			}
+			if remote.KeepGitDir {
+				fail("subdir", "has both 'subdir' and 'keep_git_dir' set, but a subdirectory can't keep the repository's .git directory")
+			}
+			cleanSubdir := path.Clean(remote.Subdir)
+			if path.IsAbs(remote.Subdir) || cleanSubdir == "." || cleanSubdir == ".." || strings.HasPrefix(cleanSubdir, "../") {
+				fail("subdir", "'subdir' provided as '%s', but it must be a directory inside the repository, relative to its root", remote.Subdir)
+			}
+		}
+
+		// Include & Exclude fields
+		message.Debugf("Index #%d: validating fields 'Include' & 'Exclude' for %+v", remoteIndex, remote)
+		if remote.HasFilters() && remote.KeepGitDir {
+			fail("keep_git_dir", "has both include/exclude patterns and 'keep_git_dir' set, but filtering would leave the clone with uncommitted changes")
+		}
+		for _, filter := range []struct {
+			field    string
+			patterns []string
+		}{
+			{field: "include", patterns: remote.Include},
+			{field: "exclude", patterns: remote.Exclude},
+		} {
+			for _, pattern := range filter.patterns {
+				if pattern == "" || !doublestar.ValidatePattern(pattern) || path.IsAbs(pattern) {
+					fail(filter.field, "'%s' pattern provided as '%s', but it must be a valid glob pattern relative to the local path", filter.field, pattern)
+				}
+			}
+		}
+
+		// SHA256 & SHA512 fields
+		message.Debugf("Index #%d: validating fields 'SHA256' & 'SHA512' for %+v", remoteIndex, remote)
+		for _, digest := range []struct {
+			field  string
+			value  string
+			length int
+		}{
+			{field: "sha256", value: remote.SHA256, length: 64},
+			{field: "sha512", value: remote.SHA512, length: 128},
+		} {
+			if digest.value == "" {
+				continue
+			}
+			if remote.Type != FileType {
+				fail(digest.field, "has a '%s' field, but only the '%s' remote type can be pinned to a digest", digest.field, FileType)
+			}
+			if !hexDigestRegex.MatchString(digest.value) || len(digest.value) != digest.length {
+				fail(digest.field, "'%s' provided as '%s', but it must be a %d-character hex string", digest.field, digest.value, digest.length)
+			}
+		}
+
+		// Archive & StripComponents fields
+		message.Debugf("Index #%d: validating fields 'Archive' & 'StripComponents' for %+v", remoteIndex, remote)
+		if (remote.Archive != "" || remote.StripComponents != 0) && remote.Type != FileType {
+			fail("archive", "has archive options set, but only the '%s' remote type can be unpacked", FileType)
+		}
+		archiveMap := map[string]int{
+			"":            1, // detected
+			ArchiveNone:   2,
+			ArchiveTar:    3,
+			ArchiveTarGz:  4,
+			ArchiveTarXz:  5,
+			ArchiveTarBz2: 6,
+			ArchiveTarZst: 7,
+			ArchiveZip:    8,
+		}
+		if _, ok := archiveMap[remote.Archive]; !ok {
+			fail("archive", "has unrecognized archive format '%s'", remote.Archive)
+		}
+		if remote.StripComponents < 0 {
+			fail("strip_components", "'strip_components' provided as %d, but it can't be negative", remote.StripComponents)
+		}
+		if remote.StripComponents != 0 && remote.Archive == ArchiveNone {
+			fail("strip_components", "has 'strip_components' set, but its archive format is '%s'", ArchiveNone)
 		}
 
 		// Type field
@@ -50,15 +203,78 @@ func (spec Spec) Validate() error {
 			FileType: 3,
 		}
 		if _, ok := typeMap[remote.Type]; !ok {
-			allErrors = append(allErrors, fmt.Errorf("unrecognized remote type '%s'", remote.Type))
+			fail("type", "unrecognized remote type '%s'", remote.Type)
 		}
 	}
 
-	if len(allErrors) > 0 {
-		for _, err := range allErrors {
-			message.Errorf("validation failure: %s", err.Error())
+	return allErrors
+}
+
+// describeRemote describes the remote at the provided index for validation
+// failures. If the spec was read from a specfile, that includes the file, line,
+// and column of the provided field (or of the remote itself, if the field isn't
+// set).
+func (spec Spec) describeRemote(remoteIndex int, field string) string {
+	remote := spec.Remotes[remoteIndex]
+	name := remote.DisplayName()
+	if remote.LocalPath == "" && remote.Name == "" {
+		name = remote.Remote
+	}
+
+	if spec.source == nil || remoteIndex >= len(spec.source.remotes) {
+		return fmt.Sprintf("remote #%d '%s'", remoteIndex, name)
+	}
+
+	node := spec.source.remotes[remoteIndex]
+	if value := mappingValue(node, field); value != nil {
+		node = value
+	}
+	return fmt.Sprintf("%s:%d:%d: remote '%s'", spec.source.path, node.Line, node.Column, name)
+}
+
+// validateLocalPath checks that the remote's local path is somewhere that's
+// safe for vdm to write to, and later prune. It returns the reason it isn't.
+func validateLocalPath(remote Remote) error {
+	localPath := filepath.ToSlash(remote.LocalPath)
+	cleanPath := path.Clean(localPath)
+
+	if strings.HasPrefix(localPath, "~") {
+		return errors.New("vdm doesn't expand '~' to a home directory, so it would make a directory named '~' instead")
+	}
+	for _, element := range strings.Split(cleanPath, "/") {
+		if element == ".git" {
+			return errors.New("it can't be inside a '.git' directory")
 		}
-		return fmt.Errorf("%d validation failure(s) found in your vdm spec file", len(allErrors))
 	}
+
+	isAbs := path.IsAbs(cleanPath) || filepath.IsAbs(remote.LocalPath)
+	if cleanPath == "." || containsProjectRoot(remote.LocalPath) {
+		return errors.New("it can't be (or contain) the project root, since that's where the spec file is")
+	}
+	switch {
+	case remote.AllowOutsideProject:
+		return nil
+	case isAbs:
+		return errors.New("it must be relative to the project root (set 'allow_outside_project' to allow absolute paths)")
+	case cleanPath == ".." || strings.HasPrefix(cleanPath, "../"):
+		return errors.New("it must be inside the project root (set 'allow_outside_project' to allow paths outside of it)")
+	}
+
 	return nil
 }
+
+// containsProjectRoot reports whether the local path is the project root (i.e.
+// the directory vdm is run from), or one of its parent directories.
+func containsProjectRoot(localPath string) bool {
+	projectRoot, err := os.Getwd()
+	if err != nil {
+		return false
+	}
+	absPath, err := filepath.Abs(localPath)
+	if err != nil {
+		return false
+	}
+
+	rel, err := filepath.Rel(absPath, projectRoot)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -1,6 +1,8 @@
 package vdmspec
 
 import (
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -20,6 +22,11 @@ func TestValidate(t *testing.T) {
 		require.NoError(t, err)
 	})
 
+	t.Run("describes remotes without a specfile by index", func(t *testing.T) {
+		spec := Spec{Remotes: []Remote{{Remote: "https://some-remote", LocalPath: "./deps/some-remote"}}}
+		assert.Equal(t, "remote #0 'some-remote'", spec.describeRemote(0, "version"))
+	})
+
 	t.Run("fails on zero-length remote", func(t *testing.T) {
 		spec := Spec{
 			Remotes: []Remote{{
@@ -81,4 +88,280 @@ func TestValidate(t *testing.T) {
 		err := spec.Validate()
 		assert.Error(t, err)
 	})
+
+	t.Run("passes on pinned digests for file remote type", func(t *testing.T) {
+		spec := Spec{
+			Remotes: []Remote{{
+				Type:      FileType,
+				Remote:    "https://some-remote/some.file",
+				LocalPath: "./deps/some.file",
+				SHA256:    strings.Repeat("a", 64),
+				SHA512:    strings.Repeat("B", 128),
+			}},
+		}
+		err := spec.Validate()
+		require.NoError(t, err)
+	})
+
+	t.Run("fails on malformed digests", func(t *testing.T) {
+		for _, remote := range []Remote{
+			{SHA256: strings.Repeat("a", 63)},
+			{SHA256: strings.Repeat("z", 64)},
+			{SHA512: strings.Repeat("a", 64)},
+		} {
+			remote.Type = FileType
+			remote.Remote = "https://some-remote/some.file"
+			remote.LocalPath = "./deps/some.file"
+			err := Spec{Remotes: []Remote{remote}}.Validate()
+			assert.Error(t, err)
+		}
+	})
+
+	t.Run("fails on digest for git remote type", func(t *testing.T) {
+		spec := Spec{
+			Remotes: []Remote{{
+				Remote:    "https://some-remote",
+				Version:   "v1.0.0",
+				LocalPath: "./deps/some-remote",
+				SHA256:    strings.Repeat("a", 64),
+			}},
+		}
+		err := spec.Validate()
+		assert.Error(t, err)
+	})
+
+	t.Run("archive options", func(t *testing.T) {
+		fileRemote := Remote{
+			Type:      FileType,
+			Remote:    "https://some-remote/release.tar.gz",
+			LocalPath: "./deps/release",
+		}
+
+		t.Run("pass for file remote type", func(t *testing.T) {
+			remote := fileRemote
+			remote.Archive = ArchiveTarGz
+			remote.StripComponents = 1
+			require.NoError(t, Spec{Remotes: []Remote{remote}}.Validate())
+		})
+
+		t.Run("fail on unrecognized format", func(t *testing.T) {
+			remote := fileRemote
+			remote.Archive = "rar"
+			assert.Error(t, Spec{Remotes: []Remote{remote}}.Validate())
+		})
+
+		t.Run("fail on negative strip_components", func(t *testing.T) {
+			remote := fileRemote
+			remote.StripComponents = -1
+			assert.Error(t, Spec{Remotes: []Remote{remote}}.Validate())
+		})
+
+		t.Run("fail on strip_components without unpacking", func(t *testing.T) {
+			remote := fileRemote
+			remote.Archive = ArchiveNone
+			remote.StripComponents = 1
+			assert.Error(t, Spec{Remotes: []Remote{remote}}.Validate())
+		})
+
+		t.Run("fail for git remote type", func(t *testing.T) {
+			remote := Remote{Remote: "https://some-remote", Version: "v1.0.0", LocalPath: "./deps/some-remote", Archive: ArchiveZip}
+			assert.Error(t, Spec{Remotes: []Remote{remote}}.Validate())
+		})
+	})
+
+	t.Run("subdir", func(t *testing.T) {
+		gitRemote := Remote{
+			Remote:    "https://some-remote",
+			Version:   "v1.0.0",
+			LocalPath: "./deps/protos",
+		}
+
+		t.Run("passes for git remote type", func(t *testing.T) {
+			remote := gitRemote
+			remote.Subdir = "proto/v1"
+			require.NoError(t, Spec{Remotes: []Remote{remote}}.Validate())
+		})
+
+		t.Run("fails outside the repository", func(t *testing.T) {
+			for _, subdir := range []string{"/proto", "..", "../other", "proto/../..", "."} {
+				remote := gitRemote
+				remote.Subdir = subdir
+				assert.Error(t, Spec{Remotes: []Remote{remote}}.Validate(), subdir)
+			}
+		})
+
+		t.Run("fails with keep_git_dir", func(t *testing.T) {
+			remote := gitRemote
+			remote.Subdir = "proto"
+			remote.KeepGitDir = true
+			assert.Error(t, Spec{Remotes: []Remote{remote}}.Validate())
+		})
+
+		t.Run("fails for file remote type", func(t *testing.T) {
+			remote := gitRemote
+			remote.Type = FileType
+			remote.Version = ""
+			remote.Subdir = "proto"
+			assert.Error(t, Spec{Remotes: []Remote{remote}}.Validate())
+		})
+	})
+
+	t.Run("include & exclude", func(t *testing.T) {
+		gitRemote := Remote{
+			Remote:    "https://some-remote",
+			Version:   "v1.0.0",
+			LocalPath: "./deps/some-remote",
+		}
+
+		t.Run("pass with valid patterns", func(t *testing.T) {
+			remote := gitRemote
+			remote.Include = []string{"src/**/*.go", "go.mod"}
+			remote.Exclude = []string{"**/*_test.go", "{docs,.github}"}
+			require.NoError(t, Spec{Remotes: []Remote{remote}}.Validate())
+		})
+
+		t.Run("fail with invalid patterns", func(t *testing.T) {
+			for _, pattern := range []string{"", "[unclosed", "/abs/**"} {
+				remote := gitRemote
+				remote.Exclude = []string{pattern}
+				assert.Error(t, Spec{Remotes: []Remote{remote}}.Validate(), pattern)
+			}
+		})
+
+		t.Run("fail with keep_git_dir", func(t *testing.T) {
+			remote := gitRemote
+			remote.Exclude = []string{"docs"}
+			remote.KeepGitDir = true
+			assert.Error(t, Spec{Remotes: []Remote{remote}}.Validate())
+		})
+	})
+
+	t.Run("version constraints", func(t *testing.T) {
+		gitRemote := Remote{
+			Remote:    "https://some-remote",
+			LocalPath: "./deps/some-remote",
+		}
+
+		t.Run("pass when valid", func(t *testing.T) {
+			for _, version := range []string{"^1.2", "~0.3.0", ">=2.0.0 <3", ">= 1.0, < 2.0", "1.x || ^3"} {
+				remote := gitRemote
+				remote.Version = version
+				assert.NoError(t, Spec{Remotes: []Remote{remote}}.Validate(), version)
+			}
+		})
+
+		t.Run("fail when invalid", func(t *testing.T) {
+			for _, version := range []string{"^one", ">=1.0.0 <", "~~1"} {
+				remote := gitRemote
+				remote.Version = version
+				assert.Error(t, Spec{Remotes: []Remote{remote}}.Validate(), version)
+			}
+		})
+
+		t.Run("pass with prerelease", func(t *testing.T) {
+			remote := gitRemote
+			remote.Version = "^2.0.0"
+			remote.Prerelease = true
+			assert.NoError(t, Spec{Remotes: []Remote{remote}}.Validate())
+		})
+
+		t.Run("fail with prerelease but no constraint", func(t *testing.T) {
+			remote := gitRemote
+			remote.Version = "v2.0.0"
+			remote.Prerelease = true
+			assert.Error(t, Spec{Remotes: []Remote{remote}}.Validate())
+		})
+
+		t.Run("fail for file remote type", func(t *testing.T) {
+			remote := gitRemote
+			remote.Type = FileType
+			remote.Version = "^1.2"
+			assert.Error(t, Spec{Remotes: []Remote{remote}}.Validate())
+		})
+	})
+
+	t.Run("names", func(t *testing.T) {
+		remote := Remote{Remote: "https://some-remote", Version: "v1.0.0", LocalPath: "./deps/some-remote"}
+		other := Remote{Remote: "https://other-remote", Version: "v1.0.0", LocalPath: "./deps/other-remote"}
+
+		t.Run("pass when unique", func(t *testing.T) {
+			named := other
+			named.Name = "other_2.0"
+			assert.NoError(t, Spec{Remotes: []Remote{remote, named}}.Validate())
+		})
+
+		t.Run("fail when invalid", func(t *testing.T) {
+			for _, name := range []string{"-other", "some/other", "some other"} {
+				named := other
+				named.Name = name
+				assert.Error(t, Spec{Remotes: []Remote{remote, named}}.Validate(), name)
+			}
+		})
+
+		t.Run("fail when the same as another name", func(t *testing.T) {
+			named, otherNamed := remote, other
+			named.Name = "some-name"
+			otherNamed.Name = "some-name"
+			assert.Error(t, Spec{Remotes: []Remote{named, otherNamed}}.Validate())
+		})
+
+		t.Run("pass when derived names are the same", func(t *testing.T) {
+			nested := other
+			nested.LocalPath = "./vendor/some-remote"
+			assert.NoError(t, Spec{Remotes: []Remote{remote, nested}}.Validate())
+
+			named := other
+			named.Name = "some-remote"
+			assert.NoError(t, Spec{Remotes: []Remote{remote, named}}.Validate())
+		})
+
+		t.Run("fail with an empty tag", func(t *testing.T) {
+			tagged := remote
+			tagged.Tags = []string{"proto", " "}
+			assert.Error(t, Spec{Remotes: []Remote{tagged}}.Validate())
+		})
+	})
+
+	t.Run("local paths", func(t *testing.T) {
+		remote := Remote{Remote: "https://some-remote", Version: "v1.0.0"}
+		outside := filepath.Join(t.TempDir(), "deps")
+
+		for _, tc := range []struct {
+			localPath    string
+			allowOutside bool
+			wantErr      bool
+		}{
+			{localPath: "./deps/some-remote"},
+			{localPath: "deps/../vendor/some-remote"},
+			{localPath: "./..foo"},
+			{localPath: ".", wantErr: true},
+			{localPath: "./deps/..", wantErr: true},
+			{localPath: "..", wantErr: true, allowOutside: true},
+			{localPath: "/", wantErr: true, allowOutside: true},
+			{localPath: "../sibling", wantErr: true},
+			{localPath: "../sibling", allowOutside: true},
+			{localPath: outside, wantErr: true},
+			{localPath: outside, allowOutside: true},
+			{localPath: "~/.ssh", wantErr: true, allowOutside: true},
+			{localPath: ".git/hooks", wantErr: true},
+			{localPath: "./deps/.git", wantErr: true},
+		} {
+			remote.LocalPath = tc.localPath
+			remote.AllowOutsideProject = tc.allowOutside
+			err := Spec{Remotes: []Remote{remote}}.Validate()
+			if tc.wantErr {
+				assert.Error(t, err, "%s (allowed: %t)", tc.localPath, tc.allowOutside)
+			} else {
+				assert.NoError(t, err, "%s (allowed: %t)", tc.localPath, tc.allowOutside)
+			}
+		}
+
+		t.Run("fail when overlapping", func(t *testing.T) {
+			first := Remote{Name: "first", Remote: "https://some-remote", Version: "v1.0.0", LocalPath: "./deps/a"}
+			for _, localPath := range []string{"deps/a", "./deps/a/b", "./deps"} {
+				second := Remote{Name: "second", Remote: "https://other-remote", Version: "v1.0.0", LocalPath: localPath}
+				assert.Error(t, Spec{Remotes: []Remote{first, second}}.Validate(), localPath)
+			}
+		})
+	})
 }
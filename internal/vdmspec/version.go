@@ -0,0 +1,97 @@
+package vdmspec
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// versionConstraintChars are the characters that mark a git remote's version
+// as a semver range constraint. None of them are allowed in git ref names (or
+// are at least very unusual in them), so they can't be confused with a tag or
+// branch.
+const versionConstraintChars = "^~<>=*|, "
+
+// IsVersionConstraint reports whether the remote's version is a semver range
+// constraint (like '^1.2', '~0.3.0', or '>=2.0.0 <3') to be resolved against
+// the remote's tags, rather than naming a single tag, branch, or commit.
+func (r Remote) IsVersionConstraint() bool {
+	return strings.ContainsAny(r.Version, versionConstraintChars)
+}
+
+// VersionConstraint parses the remote's version as a semver range constraint.
+func (r Remote) VersionConstraint() (*semver.Constraints, error) {
+	if !r.IsVersionConstraint() {
+		return nil, fmt.Errorf("version '%s' is not a version constraint", r.Version)
+	}
+
+	constraint, err := semver.NewConstraint(r.Version)
+	if err != nil {
+		return nil, fmt.Errorf("parsing version constraint '%s': %w", r.Version, err)
+	}
+
+	return constraint, nil
+}
+
+// MatchesVersionConstraint reports whether the tag satisfies the remote's
+// version constraint. Tags that aren't semver versions never do.
+//
+// Pre-release tags only satisfy constraints that themselves name a pre-release,
+// unless the remote allows pre-releases, in which case a pre-release satisfies
+// the constraint if the release it precedes would.
+func (r Remote) MatchesVersionConstraint(tag string) (bool, error) {
+	constraint, err := r.VersionConstraint()
+	if err != nil {
+		return false, err
+	}
+
+	// Tags that aren't versions just don't match
+	version, err := semver.NewVersion(tag)
+	if err != nil {
+		return false, nil
+	}
+
+	if constraint.Check(version) {
+		return true, nil
+	}
+	if r.Prerelease && version.Prerelease() != "" {
+		release, err := version.SetPrerelease("")
+		if err != nil {
+			return false, fmt.Errorf("determining release for pre-release tag '%s': %w", tag, err)
+		}
+		return constraint.Check(&release), nil
+	}
+
+	return false, nil
+}
+
+// NewestMatchingTag returns the newest of the provided tags that satisfies the
+// remote's version constraint, per [Remote.MatchesVersionConstraint].
+func (r Remote) NewestMatchingTag(tags []string) (string, error) {
+	var (
+		newest    *semver.Version
+		newestTag string
+	)
+	for _, tag := range tags {
+		matches, err := r.MatchesVersionConstraint(tag)
+		if err != nil {
+			return "", err
+		}
+		if !matches {
+			continue
+		}
+
+		// Already parsed successfully to match
+		version := semver.MustParse(tag)
+		if newest == nil || version.GreaterThan(newest) {
+			newest, newestTag = version, tag
+		}
+	}
+
+	if newest == nil {
+		return "", fmt.Errorf("no tags on the remote satisfy version constraint '%s'", r.Version)
+	}
+
+	return newestTag, nil
+}
@@ -0,0 +1,53 @@
+package vdmspec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsVersionConstraint(t *testing.T) {
+	for version, want := range map[string]bool{
+		"^1.2":       true,
+		"~0.3.0":     true,
+		">=2.0.0 <3": true,
+		"1.x || 2.x": true,
+		"*":          true,
+		"v1.2.3":     false,
+		"1.x":        false,
+		"main":       false,
+		"latest":     false,
+		"release/v1": false,
+	} {
+		assert.Equal(t, want, Remote{Version: version}.IsVersionConstraint(), version)
+	}
+}
+
+func TestNewestMatchingTag(t *testing.T) {
+	tags := []string{"v0.3.0", "v0.3.4", "v1.2.0", "v1.9.1", "v2.0.0-rc.1", "v2.0.0", "v2.1.0-beta.1", "not-a-version"}
+
+	for _, tc := range []struct {
+		version    string
+		prerelease bool
+		want       string
+	}{
+		{version: "^1.2", want: "v1.9.1"},
+		{version: "~0.3.0", want: "v0.3.4"},
+		{version: ">=1.0.0 <2", want: "v1.9.1"},
+		{version: "^2", want: "v2.0.0"},
+		{version: "^2", prerelease: true, want: "v2.1.0-beta.1"},
+		{version: ">=2.1.0-0", want: "v2.1.0-beta.1"},
+		{version: "<2.0.0", prerelease: true, want: "v1.9.1"},
+	} {
+		remote := Remote{Version: tc.version, Prerelease: tc.prerelease}
+		got, err := remote.NewestMatchingTag(tags)
+		require.NoError(t, err, tc.version)
+		assert.Equal(t, tc.want, got, tc.version)
+	}
+
+	t.Run("fails if nothing matches", func(t *testing.T) {
+		_, err := Remote{Version: "^3"}.NewestMatchingTag(tags)
+		assert.Error(t, err)
+	})
+}